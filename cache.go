@@ -0,0 +1,636 @@
+package qst
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+// cacheableStatusCodes are the status codes which are cacheable by default (RFC 9110 section 15.1).
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// CacheStorage stores cached responses by key.
+type CacheStorage interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+// Cache is a private HTTP cache (RFC 9111) of GET responses.
+//
+// It honors the "Cache-Control" and "Expires" headers of responses, revalidates stale responses with their "ETag" and
+// "Last-Modified" headers, matches responses to requests by their "Vary" headers, and serves stale responses within
+// "stale-while-revalidate" while revalidating them in the background. Requests can control the cache with their
+// "Cache-Control" header, e.g. with WithCacheControl. Responses to requests with "Authorization" or "Cookie" headers
+// are only served to requests with the same ones.
+type Cache struct {
+	Storage CacheStorage
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	mutex        sync.Mutex
+	revalidating map[string]bool
+}
+
+// NewCache returns a new *Cache which stores responses in storage.
+func NewCache(storage CacheStorage) *Cache {
+	return &Cache{Storage: storage}
+}
+
+// WithCacheControl sets the "Cache-Control" header of the *http.Request, e.g. "no-cache", "max-stale=60", or "only-if-cached".
+func WithCacheControl(directives ...string) option.Option[*http.Request] {
+	return WithHeader("Cache-Control", strings.Join(directives, ", "))
+}
+
+// RoundTripper wraps next, serving responses from the cache when they are fresh enough.
+func (c *Cache) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		if request.Method != http.MethodGet {
+			response, err := next.RoundTrip(request)
+			if err == nil && !isSafeMethod(request.Method) && response.StatusCode < 400 {
+				c.Storage.Delete(cacheKey(request))
+			}
+
+			return response, err
+		}
+
+		requestDirectives := parseCacheControl(request.Header)
+		if _, noStore := requestDirectives["no-store"]; noStore || hasConditionalHeaders(request) {
+			return next.RoundTrip(request)
+		}
+
+		entry := c.lookup(request)
+		_, onlyIfCached := requestDirectives["only-if-cached"]
+		if entry == nil {
+			if onlyIfCached {
+				return gatewayTimeout(request), nil
+			}
+
+			return c.fetch(next, request)
+		}
+
+		now := c.now()
+		age, lifetime := entry.age(now), entry.freshnessLifetime()
+		responseDirectives := parseCacheControl(entry.Header)
+		if isFreshEnough(requestDirectives, responseDirectives, age, lifetime) {
+			return entry.response(request, age), nil
+		}
+
+		if window, ok := directiveSeconds(responseDirectives, "stale-while-revalidate"); ok && !isNoCache(requestDirectives, responseDirectives) && age-lifetime <= window {
+			response := entry.response(request, age)
+			c.revalidateInBackground(next, request, entry)
+			return response, nil
+		}
+
+		if onlyIfCached {
+			return gatewayTimeout(request), nil
+		}
+
+		return c.revalidate(next, request, entry)
+	})
+}
+
+func (c *Cache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+
+	return time.Now()
+}
+
+// lookup returns the stored entry for request, if there is one and it varies on the same request headers.
+func (c *Cache) lookup(request *http.Request) *cacheEntry {
+	raw, ok := c.Storage.Get(cacheKey(request))
+	if !ok {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil
+	}
+
+	for name, values := range entry.VaryHeaders {
+		if strings.Join(request.Header.Values(name), ", ") != strings.Join(values, ", ") {
+			return nil
+		}
+	}
+
+	return &entry
+}
+
+// fetch sends request, storing the response if it is cacheable.
+func (c *Cache) fetch(next http.RoundTripper, request *http.Request) (*http.Response, error) {
+	requestTime := c.now()
+	response, err := next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.store(request, response, requestTime)
+}
+
+// revalidate sends request conditionally on the validators of entry, serving entry if it's not modified.
+func (c *Cache) revalidate(next http.RoundTripper, request *http.Request, entry *cacheEntry) (*http.Response, error) {
+	conditional := request.Clone(request.Context())
+	if etag := entry.Header.Get("ETag"); etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	requestTime := c.now()
+	response, err := next.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusNotModified {
+		return c.store(request, response, requestTime)
+	}
+
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	entry.freshen(response.Header, requestTime, c.now())
+	c.save(request, entry)
+	return entry.response(request, entry.age(c.now())), nil
+}
+
+// revalidateInBackground revalidates entry without blocking, unless it's already being revalidated.
+func (c *Cache) revalidateInBackground(next http.RoundTripper, request *http.Request, entry *cacheEntry) {
+	key := cacheKey(request)
+
+	c.mutex.Lock()
+	if c.revalidating == nil {
+		c.revalidating = make(map[string]bool)
+	}
+
+	if c.revalidating[key] {
+		c.mutex.Unlock()
+		return
+	}
+
+	c.revalidating[key] = true
+	c.mutex.Unlock()
+
+	background := request.Clone(context.Background())
+	go func() {
+		defer func() {
+			c.mutex.Lock()
+			delete(c.revalidating, key)
+			c.mutex.Unlock()
+		}()
+
+		if response, err := c.revalidate(next, background, entry); err == nil {
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+	}()
+}
+
+// store stores response if it's cacheable, returning it with its body buffered.
+func (c *Cache) store(request *http.Request, response *http.Response, requestTime time.Time) (*http.Response, error) {
+	if !isCacheable(request, response) {
+		return response, nil
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	entry := &cacheEntry{
+		StatusCode:   response.StatusCode,
+		Header:       response.Header.Clone(),
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: c.now(),
+		VaryHeaders:  make(http.Header),
+	}
+
+	for _, name := range varyHeaders(response.Header) {
+		entry.VaryHeaders[name] = request.Header.Values(name)
+	}
+
+	c.save(request, entry)
+	return response, nil
+}
+
+func (c *Cache) save(request *http.Request, entry *cacheEntry) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	c.Storage.Set(cacheKey(request), raw)
+}
+
+// cacheEntry is a stored response.
+type cacheEntry struct {
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	RequestTime  time.Time
+	ResponseTime time.Time
+	VaryHeaders  http.Header
+}
+
+// response returns a new *http.Response for the entry, with its "Age" header set to age.
+func (e *cacheEntry) response(request *http.Request, age time.Duration) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       request,
+	}
+}
+
+// age returns the current age of the entry (RFC 9111 section 4.2.3).
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		date = e.ResponseTime
+	}
+
+	apparentAge := e.ResponseTime.Sub(date)
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+
+	ageValue, _ := strconv.ParseInt(e.Header.Get("Age"), 10, 64)
+	correctedAge := time.Duration(ageValue)*time.Second + e.ResponseTime.Sub(e.RequestTime)
+	if correctedAge < apparentAge {
+		correctedAge = apparentAge
+	}
+
+	return correctedAge + now.Sub(e.ResponseTime)
+}
+
+// freshnessLifetime returns how long the entry is fresh for (RFC 9111 section 4.2.1).
+func (e *cacheEntry) freshnessLifetime() time.Duration {
+	if maxAge, ok := directiveSeconds(parseCacheControl(e.Header), "max-age"); ok {
+		return maxAge
+	}
+
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		date = e.ResponseTime
+	}
+
+	if expiresHeader := e.Header.Get("Expires"); expiresHeader != "" {
+		expires, err := http.ParseTime(expiresHeader)
+		if err != nil {
+			return 0
+		}
+
+		return expires.Sub(date)
+	}
+
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && cacheableStatusCodes[e.StatusCode] {
+		if heuristic := date.Sub(lastModified) / 10; heuristic > 0 {
+			return heuristic
+		}
+	}
+
+	return 0
+}
+
+// freshen updates the entry with the headers of a 304 response (RFC 9111 section 4.3.4).
+func (e *cacheEntry) freshen(header http.Header, requestTime, responseTime time.Time) {
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Transfer-Encoding", "Content-Encoding", "Content-Range":
+			continue
+		}
+
+		e.Header[name] = values
+	}
+
+	e.RequestTime = requestTime
+	e.ResponseTime = responseTime
+}
+
+// isFreshEnough reports whether a stored response of age and lifetime satisfies the request (RFC 9111 section 4.2).
+func isFreshEnough(requestDirectives, responseDirectives map[string]string, age, lifetime time.Duration) bool {
+	if isNoCache(requestDirectives, responseDirectives) {
+		return false
+	}
+
+	if maxAge, ok := directiveSeconds(requestDirectives, "max-age"); ok && age > maxAge {
+		return false
+	}
+
+	if minFresh, ok := directiveSeconds(requestDirectives, "min-fresh"); ok && lifetime-age < minFresh {
+		return false
+	}
+
+	if age < lifetime {
+		return true
+	}
+
+	if _, ok := responseDirectives["must-revalidate"]; ok {
+		return false
+	}
+
+	maxStale, ok := requestDirectives["max-stale"]
+	if !ok {
+		return false
+	}
+
+	if maxStale == "" {
+		return true
+	}
+
+	staleness, ok := directiveSeconds(requestDirectives, "max-stale")
+	return ok && age-lifetime <= staleness
+}
+
+func isNoCache(requestDirectives, responseDirectives map[string]string) bool {
+	_, requestNoCache := requestDirectives["no-cache"]
+	_, responseNoCache := responseDirectives["no-cache"]
+	return requestNoCache || responseNoCache
+}
+
+// isCacheable reports whether response to request may be stored (RFC 9111 section 3).
+func isCacheable(request *http.Request, response *http.Response) bool {
+	if request.Method != http.MethodGet || !cacheableStatusCodes[response.StatusCode] {
+		return false
+	}
+
+	requestDirectives := parseCacheControl(request.Header)
+	responseDirectives := parseCacheControl(response.Header)
+	if _, ok := requestDirectives["no-store"]; ok {
+		return false
+	}
+
+	if _, ok := responseDirectives["no-store"]; ok {
+		return false
+	}
+
+	for _, name := range varyHeaders(response.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+
+	_, hasMaxAge := responseDirectives["max-age"]
+	_, isPublic := responseDirectives["public"]
+	return hasMaxAge || isPublic || response.Header.Get("Expires") != "" || response.Header.Get("ETag") != "" || response.Header.Get("Last-Modified") != ""
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+func hasConditionalHeaders(request *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if request.Header.Get(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cacheKey returns the key of the responses to request. Requests with credentials are keyed by a hash of them too,
+// so that responses for one user aren't served to another.
+func cacheKey(request *http.Request) string {
+	authorization, cookie := request.Header.Values("Authorization"), request.Header.Values("Cookie")
+	if len(authorization) == 0 && len(cookie) == 0 {
+		return request.URL.String()
+	}
+
+	credentials := sha256.Sum256([]byte(strings.Join(authorization, "\n") + "\x00" + strings.Join(cookie, "\n")))
+	return fmt.Sprintf("%s %s", request.URL, hex.EncodeToString(credentials[:]))
+}
+
+// varyHeaders returns the canonical header names of the "Vary" header.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return names
+}
+
+// parseCacheControl returns the directives of the "Cache-Control" header, keyed by lowercase name.
+// Without a "Cache-Control" header, "Pragma: no-cache" is treated as "no-cache".
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	values := header.Values("Cache-Control")
+	if len(values) == 0 && strings.EqualFold(header.Get("Pragma"), "no-cache") {
+		directives["no-cache"] = ""
+	}
+
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			name, argument := directive, ""
+			if i := strings.Index(directive, "="); i >= 0 {
+				name, argument = directive[:i], strings.Trim(strings.TrimSpace(directive[i+1:]), `"`)
+			}
+
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				directives[name] = argument
+			}
+		}
+	}
+
+	return directives
+}
+
+// directiveSeconds returns the delta-seconds argument of the directive name.
+func directiveSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	argument, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(argument, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+func gatewayTimeout(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout)),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    request,
+	}
+}
+
+// MemoryCacheStorage is a CacheStorage which keeps the most recently used entries in memory.
+type MemoryCacheStorage struct {
+	capacity int
+
+	mutex   sync.Mutex
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCacheStorage returns a *MemoryCacheStorage which holds at most capacity entries.
+func NewMemoryCacheStorage(capacity int) *MemoryCacheStorage {
+	return &MemoryCacheStorage{
+		capacity: capacity,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored for key, marking it as recently used.
+func (s *MemoryCacheStorage) Get(key string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.index[key]
+	if !ok {
+		return nil, false
+	}
+
+	s.entries.MoveToFront(element)
+	return element.Value.(*memoryCacheItem).value, true
+}
+
+// Set stores value for key, evicting the least recently used entry if the storage is full.
+func (s *MemoryCacheStorage) Set(key string, value []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.index[key]; ok {
+		element.Value.(*memoryCacheItem).value = value
+		s.entries.MoveToFront(element)
+		return
+	}
+
+	s.index[key] = s.entries.PushFront(&memoryCacheItem{key: key, value: value})
+	for s.capacity > 0 && s.entries.Len() > s.capacity {
+		oldest := s.entries.Back()
+		s.entries.Remove(oldest)
+		delete(s.index, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete removes the value stored for key.
+func (s *MemoryCacheStorage) Delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.index[key]; ok {
+		s.entries.Remove(element)
+		delete(s.index, key)
+	}
+}
+
+// DiskCacheStorage is a CacheStorage which keeps entries in files in a directory.
+// Entries which can't be read or written are treated as missing.
+type DiskCacheStorage struct {
+	dir string
+}
+
+// NewDiskCacheStorage returns a *DiskCacheStorage which keeps entries in dir, creating it if needed.
+func NewDiskCacheStorage(dir string) *DiskCacheStorage {
+	return &DiskCacheStorage{dir: dir}
+}
+
+// Get returns the value stored for key.
+func (s *DiskCacheStorage) Get(key string) ([]byte, bool) {
+	value, err := ioutil.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Set stores value for key, replacing the file atomically.
+func (s *DiskCacheStorage) Set(key string, value []byte) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return
+	}
+
+	file, err := ioutil.TempFile(s.dir, "tmp-")
+	if err != nil {
+		return
+	}
+
+	_, writeErr := file.Write(value)
+	closeErr := file.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(file.Name(), s.path(key)) != nil {
+		_ = os.Remove(file.Name())
+	}
+}
+
+// Delete removes the value stored for key.
+func (s *DiskCacheStorage) Delete(key string) {
+	_ = os.Remove(s.path(key))
+}
+
+func (s *DiskCacheStorage) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
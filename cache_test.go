@@ -0,0 +1,260 @@
+package qst_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/broothie/option"
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a time which is shared by the cache and the server.
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// cacheServer counts requests, responding with the headers set by respond and a "Date" header from clock.
+type cacheServer struct {
+	clock    *fakeClock
+	mutex    sync.Mutex
+	requests []*http.Request
+	respond  func(w http.ResponseWriter, r *http.Request)
+}
+
+func (s *cacheServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, r)
+	count := len(s.requests)
+	s.mutex.Unlock()
+
+	w.Header().Set("Date", s.clock.Now().UTC().Format(http.TimeFormat))
+
+	if s.respond != nil {
+		s.respond(w, r)
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		fmt.Fprintf(w, "response %d", count)
+	}
+}
+
+func (s *cacheServer) count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.requests)
+}
+
+func (s *cacheServer) last() *http.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests[len(s.requests)-1]
+}
+
+func newCachingClient(t *testing.T, handler *cacheServer) (*qst.Client, *fakeClock) {
+	handler.clock = &fakeClock{now: time.Now()}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cache := qst.NewCache(qst.NewMemoryCacheStorage(100))
+	cache.Now = handler.clock.Now
+
+	client := qst.NewClient(http.DefaultClient, server.URL)
+	client.Middleware = []qst.Middleware{cache.RoundTripper}
+	return client, handler.clock
+}
+
+func getBody(t *testing.T, client *qst.Client, options ...option.Option[*http.Request]) string {
+	response, err := client.Get("/cereals", options...)
+	require.NoError(t, err)
+	return readAll(t, response.Body)
+}
+
+func TestCache(t *testing.T) {
+	t.Run("max-age and etag revalidation", func(t *testing.T) {
+		handler := &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+			}
+		}}
+		client, clock := newCachingClient(t, handler)
+
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, 1, handler.count())
+
+		clock.Advance(2 * time.Minute)
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, 2, handler.count())
+		assert.Equal(t, `"v1"`, handler.last().Header.Get("If-None-Match"))
+
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, 2, handler.count())
+	})
+
+	t.Run("expires and last-modified revalidation", func(t *testing.T) {
+		lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+		var handler *cacheServer
+		handler = &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Expires", handler.clock.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+			}
+		}}
+		client, clock := newCachingClient(t, handler)
+
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, 1, handler.count())
+
+		clock.Advance(2 * time.Minute)
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, 2, handler.count())
+		assert.Equal(t, lastModified, handler.last().Header.Get("If-Modified-Since"))
+	})
+
+	t.Run("vary", func(t *testing.T) {
+		handler := &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		}}
+		client, _ := newCachingClient(t, handler)
+
+		assert.Equal(t, "response 1", getBody(t, client, qst.WithHeader("Accept-Language", "en")))
+		assert.Equal(t, "response 1", getBody(t, client, qst.WithHeader("Accept-Language", "en")))
+		assert.Equal(t, "response 2", getBody(t, client, qst.WithHeader("Accept-Language", "fr")))
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		handler := &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprintf(w, "data for %s", r.Header.Get("Authorization"))
+		}}
+		client, _ := newCachingClient(t, handler)
+
+		assert.Equal(t, "data for Bearer alice", getBody(t, client, qst.WithBearerAuth("alice")))
+		assert.Equal(t, "data for Bearer bob", getBody(t, client, qst.WithBearerAuth("bob")))
+		assert.Equal(t, "data for Bearer alice", getBody(t, client, qst.WithBearerAuth("alice")))
+		assert.Equal(t, "data for ", getBody(t, client))
+		assert.Equal(t, 3, handler.count())
+	})
+
+	t.Run("no-store", func(t *testing.T) {
+		handler := &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		}}
+		client, _ := newCachingClient(t, handler)
+
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, "response 2", getBody(t, client))
+	})
+
+	t.Run("WithCacheControl", func(t *testing.T) {
+		handler := &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}}
+		client, clock := newCachingClient(t, handler)
+
+		response, err := client.Get("/cereals", qst.WithCacheControl("only-if-cached"))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusGatewayTimeout, response.StatusCode)
+		assert.Equal(t, 0, handler.count())
+
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Equal(t, "response 2", getBody(t, client, qst.WithCacheControl("no-cache")))
+		assert.Equal(t, "no-cache", handler.last().Header.Get("Cache-Control"))
+
+		clock.Advance(90 * time.Second)
+		assert.Equal(t, "response 2", getBody(t, client, qst.WithCacheControl("max-stale=60")))
+		assert.Equal(t, "response 3", getBody(t, client, qst.WithCacheControl("max-stale=10")))
+	})
+
+	t.Run("stale-while-revalidate", func(t *testing.T) {
+		handler := &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=60")
+		}}
+		client, clock := newCachingClient(t, handler)
+
+		assert.Equal(t, "response 1", getBody(t, client))
+
+		clock.Advance(90 * time.Second)
+		assert.Equal(t, "response 1", getBody(t, client))
+		assert.Eventually(t, func() bool { return handler.count() == 2 }, time.Second, 10*time.Millisecond)
+		assert.Eventually(t, func() bool { return getBody(t, client) == "response 2" }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unsafe methods invalidate", func(t *testing.T) {
+		handler := &cacheServer{respond: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}}
+		client, _ := newCachingClient(t, handler)
+
+		assert.Equal(t, "response 1", getBody(t, client))
+		_, err := client.Post("/cereals", qst.WithBodyString("Life"))
+		require.NoError(t, err)
+		assert.Equal(t, "response 3", getBody(t, client))
+	})
+}
+
+func TestMemoryCacheStorage(t *testing.T) {
+	storage := qst.NewMemoryCacheStorage(2)
+	storage.Set("life", []byte("Life"))
+	storage.Set("chex", []byte("Chex"))
+
+	_, ok := storage.Get("life")
+	assert.True(t, ok)
+
+	storage.Set("trix", []byte("Trix"))
+	_, ok = storage.Get("chex")
+	assert.False(t, ok)
+
+	value, ok := storage.Get("life")
+	assert.True(t, ok)
+	assert.Equal(t, "Life", string(value))
+
+	storage.Delete("life")
+	_, ok = storage.Get("life")
+	assert.False(t, ok)
+}
+
+func TestDiskCacheStorage(t *testing.T) {
+	storage := qst.NewDiskCacheStorage(t.TempDir() + "/cache")
+
+	_, ok := storage.Get("life")
+	assert.False(t, ok)
+
+	storage.Set("life", []byte("Life"))
+	value, ok := storage.Get("life")
+	assert.True(t, ok)
+	assert.Equal(t, "Life", string(value))
+
+	storage.Delete("life")
+	_, ok = storage.Get("life")
+	assert.False(t, ok)
+}
@@ -0,0 +1,319 @@
+package qst
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+const (
+	defaultCircuitFailureThreshold = 5
+	defaultCircuitCoolDown         = 30 * time.Second
+)
+
+var defaultCircuitFailureStatusCodes = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// ErrCircuitOpen is returned for requests rejected by an open *CircuitBreaker. The returned error is a *CircuitOpenError.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitOpenError is returned for requests rejected by an open *CircuitBreaker.
+type CircuitOpenError struct {
+	// Key is the key of the open circuit.
+	Key string
+
+	// Until is when the circuit will allow a trial request. It is zero if the circuit is half-open and its trial
+	// requests are in flight.
+	Until time.Time
+}
+
+// Error returns a description of the CircuitOpenError.
+func (e *CircuitOpenError) Error() string {
+	if e.Until.IsZero() {
+		return fmt.Sprintf("circuit open for %s", e.Key)
+	}
+
+	return fmt.Sprintf("circuit open for %s until %s", e.Key, e.Until.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a circuit.
+type CircuitState int
+
+const (
+	// CircuitClosed allows requests, counting consecutive failures.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects requests until the cool-down has passed.
+	CircuitOpen
+
+	// CircuitHalfOpen allows a limited number of trial requests, closing if they succeed and opening if one fails.
+	CircuitHalfOpen
+)
+
+// String returns the name of the CircuitState.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker rejects requests to upstreams which are failing, with a circuit per key, by default the request's host.
+// A circuit opens after FailureThreshold consecutive failures, rejecting requests with a *CircuitOpenError. After
+// CoolDown it becomes half-open, allowing HalfOpenRequests trial requests, which close it if they all succeed.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures which open a circuit. Defaults to 5.
+	FailureThreshold int
+
+	// CoolDown is how long a circuit stays open before allowing trial requests. Defaults to 30s.
+	CoolDown time.Duration
+
+	// HalfOpenRequests is the number of trial requests allowed by a half-open circuit. Defaults to 1.
+	HalfOpenRequests int
+
+	// FailureStatusCodes are the response status codes which count as failures. Defaults to 500, 502, 503, and 504.
+	FailureStatusCodes []int
+
+	// IsFailure, if set, reports whether the outcome of a request counts as a failure, overriding FailureStatusCodes.
+	// By default, errors are failures. Requests abandoned by canceling their context are never counted.
+	IsFailure func(*http.Response, error) bool
+
+	// Key returns the circuit key of a request. If nil, the request's host is used.
+	Key func(*http.Request) string
+
+	// OnStateChange, if set, is called when a circuit changes state.
+	OnStateChange func(key string, from, to CircuitState)
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	trials    int
+	successes int
+}
+
+// NewCircuitBreaker returns a *CircuitBreaker which opens after failureThreshold consecutive failures, for coolDown.
+func NewCircuitBreaker(failureThreshold int, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{FailureThreshold: failureThreshold, CoolDown: coolDown}
+}
+
+// WithCircuitBreaker sends the *http.Request through breaker. The breaker should be shared between requests, e.g. by
+// reusing the option or adding breaker.RoundTripper to Client.Middleware.
+func WithCircuitBreaker(breaker *CircuitBreaker) option.Option[*http.Request] {
+	return WithMiddleware(breaker.RoundTripper)
+}
+
+// RoundTripper wraps next, rejecting requests whose circuit is open and recording the outcome of the others.
+func (b *CircuitBreaker) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		key := b.key(request)
+		if err := b.allow(key); err != nil {
+			return nil, err
+		}
+
+		response, err := next.RoundTrip(request)
+		if err != nil && request.Context().Err() != nil && errors.Is(err, context.Canceled) {
+			b.release(key)
+			return nil, err
+		}
+
+		b.record(key, b.isFailure(response, err))
+		return response, err
+	})
+}
+
+// State returns the state of the circuit for key.
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if circuit, ok := b.circuits[key]; ok {
+		return circuit.state
+	}
+
+	return CircuitClosed
+}
+
+// allow returns a *CircuitOpenError if the circuit for key doesn't allow a request.
+func (b *CircuitBreaker) allow(key string) error {
+	b.mutex.Lock()
+	circuit := b.circuit(key)
+	from := circuit.state
+
+	var err error
+	switch circuit.state {
+	case CircuitOpen:
+		until := circuit.openedAt.Add(b.coolDown())
+		if time.Now().Before(until) {
+			err = &CircuitOpenError{Key: key, Until: until}
+			break
+		}
+
+		circuit.state, circuit.trials, circuit.successes = CircuitHalfOpen, 1, 0
+
+	case CircuitHalfOpen:
+		if circuit.trials >= b.halfOpenRequests() {
+			err = &CircuitOpenError{Key: key}
+			break
+		}
+
+		circuit.trials++
+	}
+
+	to := circuit.state
+	b.mutex.Unlock()
+
+	b.stateChanged(key, from, to)
+	return err
+}
+
+// release gives back a trial request of the circuit for key, without recording an outcome.
+func (b *CircuitBreaker) release(key string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if circuit := b.circuit(key); circuit.state == CircuitHalfOpen && circuit.trials > 0 {
+		circuit.trials--
+	}
+}
+
+// record updates the circuit for key with the outcome of a request.
+func (b *CircuitBreaker) record(key string, failure bool) {
+	b.mutex.Lock()
+	circuit := b.circuit(key)
+	from := circuit.state
+
+	switch circuit.state {
+	case CircuitClosed:
+		if !failure {
+			circuit.failures = 0
+			break
+		}
+
+		circuit.failures++
+		if circuit.failures >= b.failureThreshold() {
+			circuit.state, circuit.openedAt = CircuitOpen, time.Now()
+		}
+
+	case CircuitHalfOpen:
+		if failure {
+			circuit.state, circuit.openedAt = CircuitOpen, time.Now()
+			break
+		}
+
+		circuit.successes++
+		if circuit.successes >= b.halfOpenRequests() {
+			circuit.state, circuit.failures = CircuitClosed, 0
+		}
+	}
+
+	to := circuit.state
+	b.mutex.Unlock()
+
+	b.stateChanged(key, from, to)
+}
+
+func (b *CircuitBreaker) stateChanged(key string, from, to CircuitState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(key, from, to)
+	}
+}
+
+// circuit returns the circuit for key. It must be called with the mutex held.
+func (b *CircuitBreaker) circuit(key string) *circuit {
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+
+	return c
+}
+
+func (b *CircuitBreaker) isFailure(response *http.Response, err error) bool {
+	if b.IsFailure != nil {
+		return b.IsFailure(response, err)
+	}
+
+	if err != nil {
+		return true
+	}
+
+	statusCodes := b.FailureStatusCodes
+	if statusCodes == nil {
+		statusCodes = defaultCircuitFailureStatusCodes
+	}
+
+	for _, statusCode := range statusCodes {
+		if response.StatusCode == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *CircuitBreaker) key(request *http.Request) string {
+	if b.Key != nil {
+		return b.Key(request)
+	}
+
+	if request.Host != "" {
+		return request.Host
+	}
+
+	return request.URL.Host
+}
+
+func (b *CircuitBreaker) failureThreshold() int {
+	if b.FailureThreshold <= 0 {
+		return defaultCircuitFailureThreshold
+	}
+
+	return b.FailureThreshold
+}
+
+func (b *CircuitBreaker) coolDown() time.Duration {
+	if b.CoolDown <= 0 {
+		return defaultCircuitCoolDown
+	}
+
+	return b.CoolDown
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.HalfOpenRequests <= 0 {
+		return 1
+	}
+
+	return b.HalfOpenRequests
+}
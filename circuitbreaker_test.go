@@ -0,0 +1,107 @@
+package qst_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var status int32 = http.StatusServiceUnavailable
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer otherServer.Close()
+
+	var mutex sync.Mutex
+	var transitions []string
+	breaker := qst.NewCircuitBreaker(2, 50*time.Millisecond)
+	breaker.OnStateChange = func(key string, from, to qst.CircuitState) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		transitions = append(transitions, fmt.Sprintf("%s -> %s", from, to))
+	}
+
+	client := qst.NewClient(http.DefaultClient, server.URL)
+	client.Middleware = []qst.Middleware{breaker.RoundTripper}
+	key := server.Listener.Addr().String()
+
+	for i := 0; i < 2; i++ {
+		response, err := client.Post("/cereals")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	}
+
+	assert.Equal(t, qst.CircuitOpen, breaker.State(key))
+
+	_, err := client.Post("/cereals")
+	assert.ErrorIs(t, err, qst.ErrCircuitOpen)
+
+	var circuitOpenError *qst.CircuitOpenError
+	require.True(t, errors.As(err, &circuitOpenError))
+	assert.Equal(t, key, circuitOpenError.Key)
+	assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), circuitOpenError.Until, 50*time.Millisecond)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+
+	t.Run("per host", func(t *testing.T) {
+		_, err := client.Post(otherServer.URL)
+		assert.NoError(t, err)
+	})
+
+	t.Run("reopens when a trial fails", func(t *testing.T) {
+		time.Sleep(60 * time.Millisecond)
+		response, err := client.Post("/cereals")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+		assert.Equal(t, qst.CircuitOpen, breaker.State(key))
+	})
+
+	t.Run("closes when a trial succeeds", func(t *testing.T) {
+		atomic.StoreInt32(&status, http.StatusOK)
+		time.Sleep(60 * time.Millisecond)
+
+		response, err := client.Post("/cereals")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, qst.CircuitClosed, breaker.State(key))
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, transitions)
+}
+
+func TestCircuitBreaker_errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	breaker := &qst.CircuitBreaker{FailureThreshold: 1, CoolDown: time.Minute}
+	_, err := qst.Get(url, qst.WithCircuitBreaker(breaker))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, qst.ErrCircuitOpen)
+
+	_, err = qst.Get(url, qst.WithCircuitBreaker(breaker))
+	assert.ErrorIs(t, err, qst.ErrCircuitOpen)
+}
@@ -1,11 +1,185 @@
 package qst
 
-import "net/http"
+import (
+	"net/http"
+	pkgurl "net/url"
+	"strings"
+	"sync/atomic"
 
-// client is the global HTTP client used by the Do function.
-var client = http.DefaultClient
+	"github.com/broothie/option"
+)
 
-// SetClient sets the global HTTP client used by the Do function.
+// Client builds and makes requests against a base URL, applying a set of default options to every request.
+type Client struct {
+	// HTTPClient is the *http.Client used to make requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// BaseURL is the URL that request URLs are resolved against. Relative request URLs are appended to its path.
+	BaseURL string
+
+	// Defaults are applied to every request, before any per-call options.
+	Defaults []option.Option[*http.Request]
+
+	// Retry, if set, is the *RetryPolicy used for requests which don't set their own with WithRetry.
+	Retry *RetryPolicy
+
+	// Dumper, if set, is the *RoundTripDumper used for requests which don't set their own with WithRoundTripDump.
+	// Each retry attempt is dumped separately.
+	Dumper *RoundTripDumper
+
+	// Signer, if set, signs requests which don't set their own signers with WithMessageSignature.
+	// Requests are signed after all middleware and retries, just before they are dumped and sent.
+	Signer *MessageSigner
+
+	// Middleware wrap the transport of every request, outside of any applied with WithMiddleware.
+	Middleware []Middleware
+}
+
+// NewClient returns a new *Client.
+func NewClient(httpClient *http.Client, baseURL string, options ...option.Option[*http.Request]) *Client {
+	return &Client{
+		HTTPClient: httpClient,
+		BaseURL:    baseURL,
+		Defaults:   options,
+	}
+}
+
+// New builds a new *http.Request, resolving url against the BaseURL and applying the default options before options.
+func (c *Client) New(method, url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	resolved, err := c.resolveURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(method, resolved, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	allOptions := make([]option.Option[*http.Request], 0, len(c.Defaults)+len(options))
+	allOptions = append(allOptions, c.Defaults...)
+	allOptions = append(allOptions, options...)
+
+	return option.Apply(request, allOptions...)
+}
+
+// Do makes an *http.Request using the *Client and returns the *http.Response.
+// If the status code is not expected, the returned error is a *StatusError and the response body is closed.
+func (c *Client) Do(method, url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	request, err := c.New(method, url, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient(request).Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkStatus(request, response)
+}
+
+// httpClient returns the *http.Client used to send request, with its transport wrapped as the request requires.
+func (c *Client) httpClient(request *http.Request) *http.Client {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	retry := c.Retry
+	if policy, ok := request.Context().Value(retryPolicyKey{}).(*RetryPolicy); ok {
+		retry = policy
+	}
+
+	dumper := c.Dumper
+	if requestDumper, ok := request.Context().Value(roundTripDumperKey{}).(*RoundTripDumper); ok {
+		dumper = requestDumper
+	}
+
+	var middleware []Middleware
+	middleware = append(middleware, c.Middleware...)
+	middleware = append(middleware, requestMiddleware(request)...)
+	if retry != nil {
+		middleware = append(middleware, retry.RoundTripper)
+	}
+
+	if signing := signingMiddleware(request, c.Signer); signing != nil {
+		middleware = append(middleware, signing)
+	}
+
+	if dumper != nil {
+		middleware = append(middleware, dumper.RoundTripper)
+	}
+
+	if len(middleware) == 0 {
+		return httpClient
+	}
+
+	wrapped := *httpClient
+	wrapped.Transport = Chain(middleware...)(orDefaultTransport(httpClient.Transport))
+	return &wrapped
+}
+
+func (c *Client) resolveURL(url string) (string, error) {
+	if url == "" {
+		return c.BaseURL, nil
+	}
+
+	if c.BaseURL == "" {
+		return url, nil
+	}
+
+	reference, err := pkgurl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+
+	if reference.IsAbs() {
+		return url, nil
+	}
+
+	base, err := pkgurl.Parse(c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		if base.RawPath != "" {
+			base.RawPath += "/"
+		}
+	}
+
+	reference.Path = strings.TrimPrefix(reference.Path, "/")
+	reference.RawPath = strings.TrimPrefix(reference.RawPath, "/")
+	return base.ResolveReference(reference).String(), nil
+}
+
+// defaultClient is the global *Client used by the package-level functions.
+var defaultClient atomic.Pointer[Client]
+
+func init() {
+	defaultClient.Store(NewClient(http.DefaultClient, ""))
+}
+
+// DefaultClient returns the global *Client used by the package-level functions.
+func DefaultClient() *Client {
+	return defaultClient.Load()
+}
+
+// SetDefaultClient sets the global *Client used by the package-level functions.
+func SetDefaultClient(c *Client) {
+	defaultClient.Store(c)
+}
+
+// SetClient sets the *http.Client used by the package-level functions, keeping the rest of the default *Client.
 func SetClient(c *http.Client) {
-	client = c
+	for {
+		current := defaultClient.Load()
+		updated := *current
+		updated.HTTPClient = c
+		if defaultClient.CompareAndSwap(current, &updated) {
+			return
+		}
+	}
 }
@@ -1,12 +1,15 @@
 package qst_test
 
 import (
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/broothie/qst"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSetClient(t *testing.T) {
@@ -17,13 +20,100 @@ func TestSetClient(t *testing.T) {
 
 	// Set the custom client
 	qst.SetClient(customClient)
+	assert.Equal(t, customClient, qst.DefaultClient().HTTPClient)
 
-	// Create a request that would use the custom client
-	// We can't easily test the exact client being used without making an actual request,
-	// but we can at least verify the function doesn't panic
 	_, err := qst.New(http.MethodGet, "https://example.com")
 	assert.NoError(t, err)
 
 	// Reset to default client to avoid affecting other tests
 	qst.SetClient(http.DefaultClient)
+
+	t.Run("keeps the rest of the default client", func(t *testing.T) {
+		original := qst.DefaultClient()
+		defer qst.SetDefaultClient(original)
+
+		retry := &qst.RetryPolicy{MaxAttempts: 5}
+		qst.SetDefaultClient(&qst.Client{BaseURL: "https://breakfast.com/api", Retry: retry})
+		qst.SetClient(customClient)
+
+		client := qst.DefaultClient()
+		assert.Equal(t, customClient, client.HTTPClient)
+		assert.Equal(t, "https://breakfast.com/api", client.BaseURL)
+		assert.Equal(t, retry, client.Retry)
+	})
+}
+
+func TestClient_New(t *testing.T) {
+	testCases := map[string]struct {
+		baseURL  string
+		url      string
+		expected string
+	}{
+		"no base URL":           {url: "https://breakfast.com/api", expected: "https://breakfast.com/api"},
+		"empty URL":             {baseURL: "https://breakfast.com/api", expected: "https://breakfast.com/api"},
+		"relative path":         {baseURL: "https://breakfast.com/api", url: "cereals", expected: "https://breakfast.com/api/cereals"},
+		"absolute path":         {baseURL: "https://breakfast.com/api/", url: "/cereals/1234", expected: "https://breakfast.com/api/cereals/1234"},
+		"query only":            {baseURL: "https://breakfast.com/api", url: "?page=2", expected: "https://breakfast.com/api/?page=2"},
+		"path and query":        {baseURL: "https://breakfast.com/api", url: "cereals?page=2", expected: "https://breakfast.com/api/cereals?page=2"},
+		"absolute URL override": {baseURL: "https://breakfast.com/api", url: "https://lunch.com/sandwiches", expected: "https://lunch.com/sandwiches"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := qst.NewClient(nil, tc.baseURL)
+
+			request, err := client.NewGet(tc.url)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, request.URL.String())
+		})
+	}
+
+	t.Run("defaults are applied before options", func(t *testing.T) {
+		client := qst.NewClient(nil, "https://breakfast.com/api",
+			qst.WithHeader("grain", "oats"),
+			qst.WithBearerAuth("c0rnfl@k3s"),
+		)
+
+		request, err := client.NewGet("cereals", qst.WithHeader("grain", "corn"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"oats", "corn"}, request.Header.Values("grain"))
+		assert.Equal(t, "Bearer c0rnfl@k3s", request.Header.Get("Authorization"))
+	})
+
+	t.Run("invalid base URL", func(t *testing.T) {
+		client := qst.NewClient(nil, "%")
+
+		_, err := client.NewGet("cereals")
+		assert.EqualError(t, err, `parse "%": invalid URL escape "%"`)
+	})
+}
+
+func TestClient_Do(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/api/cereals/1234", r.URL.Path)
+		assert.Equal(t, "Bearer c0rnfl@k3s", r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	client := qst.NewClient(server.Client(), server.URL+"/api", qst.WithBearerAuth("c0rnfl@k3s"))
+
+	response, err := client.Patch("/cereals/1234")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func ExampleClient() {
+	client := qst.NewClient(http.DefaultClient, "https://breakfast.com/api",
+		qst.WithBearerAuth("c0rnfl@k3s"),
+	)
+
+	request, _ := client.NewGet("/cereals/1234")
+
+	fmt.Println(request.URL)
+	fmt.Println(request.Header.Get("Authorization"))
+
+	// Output:
+	// https://breakfast.com/api/cereals/1234
+	// Bearer c0rnfl@k3s
 }
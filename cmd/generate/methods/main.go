@@ -38,6 +38,10 @@ func (m Method) ConstructorName() string {
 	return fmt.Sprintf("New%s", m.Capitalized())
 }
 
+func (m Method) HasResponseBody() bool {
+	return m != http.MethodHead
+}
+
 var methods = []Method{
 	http.MethodGet,
 	http.MethodHead,
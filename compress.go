@@ -0,0 +1,82 @@
+package qst
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/broothie/option"
+)
+
+// ErrUnsupportedContentEncoding is returned for content encodings which WithBodyCompression doesn't support.
+var ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
+
+// bodyCompressors are the content codings supported by WithBodyCompression.
+var bodyCompressors = map[string]func(io.Writer) io.WriteCloser{
+	"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+}
+
+// WithBodyCompression compresses the *http.Request body with encoding, "gzip" or "deflate", and adds it to the
+// "Content-Encoding" header. It applies to the body set by the options before it, so it must come after them: a body
+// set by a later option replaces the compressed one, but the "Content-Encoding" header is left in place.
+// Bodies which can be replayed, such as those set by WithBodyJSON, are compressed in memory, keeping them replayable.
+// Other bodies, such as those set by WithBodyReader with an arbitrary io.Reader, are compressed as they're sent.
+func WithBodyCompression(encoding string) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		newCompressor, ok := bodyCompressors[encoding]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentEncoding, encoding)
+		}
+
+		if request.Body == nil || request.Body == http.NoBody {
+			return request, nil
+		}
+
+		if contentEncoding := request.Header.Get("Content-Encoding"); contentEncoding != "" {
+			encoding = fmt.Sprintf("%s, %s", contentEncoding, encoding)
+		}
+
+		request.Header.Set("Content-Encoding", encoding)
+		if request.GetBody == nil {
+			body := request.Body
+			compressed := newPipeBody(func(w io.Writer) error {
+				defer body.Close()
+				return compress(w, body, newCompressor)
+			})
+
+			compressed.source = body
+			return WithBody(compressed).Apply(request)
+		}
+
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+
+		defer body.Close()
+		request.Body.Close()
+
+		var compressed bytes.Buffer
+		if err := compress(&compressed, body, newCompressor); err != nil {
+			return nil, err
+		}
+
+		content := compressed.Bytes()
+		return withReplayableBody(int64(len(content)), func() io.Reader { return bytes.NewReader(content) }).Apply(request)
+	})
+}
+
+// compress writes the content of r to w, compressed by a compressor from newCompressor.
+func compress(w io.Writer, r io.Reader, newCompressor func(io.Writer) io.WriteCloser) error {
+	compressor := newCompressor(w)
+	if _, err := io.Copy(compressor, r); err != nil {
+		return err
+	}
+
+	return compressor.Close()
+}
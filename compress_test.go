@@ -0,0 +1,130 @@
+package qst_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decompress(t *testing.T, encoding string, r io.Reader) string {
+	var reader io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		reader, err = gzip.NewReader(r)
+	case "deflate":
+		reader, err = zlib.NewReader(r)
+	}
+
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestWithBodyCompression(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			request, err := qst.NewPost("https://breakfast.com/api/cereals",
+				qst.WithBodyJSON(map[string]string{"name": "Life"}),
+				qst.WithBodyCompression(encoding),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, encoding, request.Header.Get("Content-Encoding"))
+			assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+
+			body, err := ioutil.ReadAll(request.Body)
+			require.NoError(t, err)
+			assert.EqualValues(t, len(body), request.ContentLength)
+			assert.Equal(t, `{"name":"Life"}`+"\n", decompress(t, encoding, strings.NewReader(string(body))))
+
+			require.NotNil(t, request.GetBody)
+			replayed, err := request.GetBody()
+			require.NoError(t, err)
+			assert.Equal(t, string(body), readAll(t, replayed))
+		})
+	}
+
+	t.Run("streaming", func(t *testing.T) {
+		var contentEncoding, body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentEncoding = r.Header.Get("Content-Encoding")
+			body = decompress(t, "gzip", r.Body)
+		}))
+		defer server.Close()
+
+		request, err := qst.NewPost(server.URL,
+			qst.WithBodyReader(struct{ io.Reader }{strings.NewReader("Life")}),
+			qst.WithBodyCompression("gzip"),
+		)
+		require.NoError(t, err)
+		assert.Nil(t, request.GetBody)
+		assert.Zero(t, request.ContentLength)
+
+		_, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", contentEncoding)
+		assert.Equal(t, "Life", body)
+	})
+
+	t.Run("streaming read error", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyReader(broken{}),
+			qst.WithBodyCompression("gzip"),
+		)
+		require.NoError(t, err)
+
+		_, err = ioutil.ReadAll(request.Body)
+		assert.EqualError(t, err, "broken")
+	})
+
+	t.Run("streaming body is closed if never sent", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+
+		body := &closeRecorder{Reader: strings.NewReader("Life")}
+		_, err := qst.Post(closed.URL, qst.WithBody(body), qst.WithBodyCompression("gzip"))
+		assert.Error(t, err)
+		assert.True(t, body.closed)
+	})
+
+	t.Run("existing content encoding", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithHeader("Content-Encoding", "deflate"),
+			qst.WithBodyString("Life"),
+			qst.WithBodyCompression("gzip"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "deflate, gzip", request.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("no body", func(t *testing.T) {
+		request, err := qst.NewGet("https://breakfast.com/api/cereals", qst.WithBodyCompression("gzip"))
+		require.NoError(t, err)
+		assert.Empty(t, request.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		_, err := qst.NewPost("https://breakfast.com/api/cereals", qst.WithBodyString("Life"), qst.WithBodyCompression("br"))
+		assert.ErrorIs(t, err, qst.ErrUnsupportedContentEncoding)
+	})
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
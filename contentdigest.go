@@ -0,0 +1,67 @@
+package qst
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/broothie/option"
+)
+
+// ErrUnsupportedDigestAlgorithm is returned for digest algorithms which aren't supported.
+var ErrUnsupportedDigestAlgorithm = errors.New("unsupported digest algorithm")
+
+// digestAlgorithms are the supported Content-Digest algorithms, by their RFC 9530 names.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+type contentDigestKey struct{}
+
+// WithContentDigest sets the "Content-Digest" header (RFC 9530) of the *http.Request when it is sent, after any
+// middleware and retries, and before any signing. Supported algorithms are "sha-256" and "sha-512". If none are
+// given, "sha-512" is used.
+func WithContentDigest(algorithms ...string) option.Option[*http.Request] {
+	if len(algorithms) == 0 {
+		algorithms = []string{"sha-512"}
+	}
+
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		for _, algorithm := range algorithms {
+			if _, ok := digestAlgorithms[algorithm]; !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnsupportedDigestAlgorithm, algorithm)
+			}
+		}
+
+		return WithContextValue(contentDigestKey{}, algorithms).Apply(request)
+	})
+}
+
+// setContentDigest sets the "Content-Digest" header of request, computed from its body with algorithms.
+func setContentDigest(request *http.Request, algorithms []string) error {
+	body, err := peekBody(request)
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+
+	digests := make([]string, len(algorithms))
+	for i, algorithm := range algorithms {
+		newHash, ok := digestAlgorithms[algorithm]
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnsupportedDigestAlgorithm, algorithm)
+		}
+
+		digest := newHash()
+		digest.Write(body)
+		digests[i] = fmt.Sprintf("%s=:%s:", algorithm, base64.StdEncoding.EncodeToString(digest.Sum(nil)))
+	}
+
+	request.Header.Set("Content-Digest", strings.Join(digests, ", "))
+	return nil
+}
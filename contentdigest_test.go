@@ -0,0 +1,36 @@
+package qst_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContentDigest(t *testing.T) {
+	var contentDigest string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentDigest = r.Header.Get("Content-Digest")
+	}))
+	defer server.Close()
+
+	t.Run("sha-512 by default", func(t *testing.T) {
+		_, err := qst.Post(server.URL, qst.WithContentDigest(), qst.WithBodyString(`{"hello": "world"}`))
+		require.NoError(t, err)
+		assert.Equal(t, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:", contentDigest)
+	})
+
+	t.Run("multiple algorithms", func(t *testing.T) {
+		_, err := qst.Post(server.URL, qst.WithContentDigest("sha-256", "sha-512"), qst.WithBodyString(`{"hello": "world"}`))
+		require.NoError(t, err)
+		assert.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:, sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:", contentDigest)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := qst.Post(server.URL, qst.WithContentDigest("md5"))
+		assert.ErrorIs(t, err, qst.ErrUnsupportedDigestAlgorithm)
+	})
+}
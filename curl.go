@@ -0,0 +1,176 @@
+package qst
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/broothie/option"
+)
+
+// ToCurl returns a curl command equivalent to the *http.Request.
+// If the body can't be replayed with GetBody, it is read and replaced.
+func ToCurl(request *http.Request) (string, error) {
+	return toCurl(request, false)
+}
+
+// ToCurlRedacted returns a curl command equivalent to the *http.Request, with credentials and cookie values redacted.
+func ToCurlRedacted(request *http.Request) (string, error) {
+	return toCurl(request, true)
+}
+
+// WithCurlDump writes the request to w as a curl command.
+func WithCurlDump(w io.Writer) option.Option[*http.Request] {
+	return withCurlDump(w, false)
+}
+
+// WithRedactedCurlDump writes the request to w as a curl command, with credentials and cookie values redacted.
+func WithRedactedCurlDump(w io.Writer) option.Option[*http.Request] {
+	return withCurlDump(w, true)
+}
+
+func withCurlDump(w io.Writer, redact bool) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		command, err := toCurl(request, redact)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := fmt.Fprintln(w, command); err != nil {
+			return nil, err
+		}
+
+		return request, nil
+	})
+}
+
+func toCurl(request *http.Request, redact bool) (string, error) {
+	body, err := peekBody(request)
+	if err != nil {
+		return "", err
+	}
+
+	// curl sends POST when there's a body, so any other method must be given explicitly.
+	args := []string{"curl"}
+	switch {
+	case len(body) > 0 && (request.Method == "" || request.Method == http.MethodGet):
+		args = append(args, "-X", http.MethodGet)
+	case request.Method == "", request.Method == http.MethodGet:
+	case request.Method == http.MethodHead && len(body) == 0:
+		args = append(args, "--head")
+	default:
+		args = append(args, "-X", shellQuote(request.Method))
+	}
+
+	url := *request.URL
+	user := url.User
+	url.User = nil
+	args = append(args, shellQuote(url.String()))
+
+	if request.Host != "" && request.Host != request.URL.Host {
+		args = append(args, "-H", shellQuote(fmt.Sprintf("Host: %s", request.Host)))
+	}
+
+	keys := make([]string, 0, len(request.Header))
+	for key := range request.Header {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range request.Header[key] {
+			switch http.CanonicalHeaderKey(key) {
+			case "Authorization":
+				if username, password, ok := request.BasicAuth(); ok {
+					if redact {
+						password = redacted
+					}
+
+					args = append(args, "-u", shellQuote(fmt.Sprintf("%s:%s", username, password)))
+					continue
+				}
+
+				if redact {
+					value = redactCredentials(value)
+				}
+
+			case "Proxy-Authorization":
+				if redact {
+					value = redactCredentials(value)
+				}
+
+			case "Cookie":
+				if redact {
+					value = redactCookies(value)
+				}
+
+				args = append(args, "-b", shellQuote(value))
+				continue
+			}
+
+			args = append(args, "-H", shellQuote(fmt.Sprintf("%s: %s", key, value)))
+		}
+	}
+
+	if _, _, hasBasicAuth := request.BasicAuth(); user != nil && !hasBasicAuth {
+		credentials := user.Username()
+		if password, ok := user.Password(); ok {
+			if redact {
+				password = redacted
+			}
+
+			credentials = fmt.Sprintf("%s:%s", credentials, password)
+		}
+
+		args = append(args, "-u", shellQuote(credentials))
+	}
+
+	if len(body) > 0 {
+		args = append(args, "--data-binary", shellQuote(string(body)))
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// peekBody returns the body of the *http.Request without consuming it.
+// If the body can't be replayed with GetBody, it is read and replaced.
+func peekBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+
+		defer body.Close()
+		return ioutil.ReadAll(body)
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := request.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,/:@=+%") == "" {
+		return s
+	}
+
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", `'\''`))
+}
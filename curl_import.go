@@ -0,0 +1,519 @@
+package qst
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	pkgurl "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/broothie/option"
+)
+
+// ErrInvalidCurl is returned when a curl command can't be parsed.
+var ErrInvalidCurl = errors.New("invalid curl command")
+
+// curlFlags maps curl's short flags to their long equivalents.
+var curlFlags = map[string]string{
+	"-X": "--request",
+	"-H": "--header",
+	"-d": "--data",
+	"-u": "--user",
+	"-b": "--cookie",
+	"-F": "--form",
+	"-A": "--user-agent",
+	"-e": "--referer",
+	"-G": "--get",
+	"-I": "--head",
+	"-s": "--silent",
+	"-S": "--show-error",
+	"-L": "--location",
+	"-k": "--insecure",
+	"-v": "--verbose",
+	"-i": "--include",
+	"-f": "--fail",
+	"-g": "--globoff",
+	"-N": "--no-buffer",
+	"-o": "--output",
+	"-m": "--max-time",
+}
+
+// curlArgFlags are the flags which take an argument.
+var curlArgFlags = map[string]bool{
+	"--request":         true,
+	"--header":          true,
+	"--data":            true,
+	"--data-ascii":      true,
+	"--data-binary":     true,
+	"--data-raw":        true,
+	"--data-urlencode":  true,
+	"--json":            true,
+	"--user":            true,
+	"--cookie":          true,
+	"--form":            true,
+	"--user-agent":      true,
+	"--referer":         true,
+	"--url":             true,
+	"--output":          true,
+	"--max-time":        true,
+	"--connect-timeout": true,
+}
+
+// curlIgnoredFlags are the flags which don't affect the request.
+var curlIgnoredFlags = map[string]bool{
+	"--silent":          true,
+	"--show-error":      true,
+	"--location":        true,
+	"--insecure":        true,
+	"--verbose":         true,
+	"--include":         true,
+	"--fail":            true,
+	"--globoff":         true,
+	"--no-buffer":       true,
+	"--http1.1":         true,
+	"--http2":           true,
+	"--output":          true,
+	"--max-time":        true,
+	"--connect-timeout": true,
+}
+
+// NewFromCurl builds a new *http.Request from a curl command.
+func NewFromCurl(command string) (*http.Request, error) {
+	options, err := FromCurl(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(http.MethodGet, "", options...)
+}
+
+// FromCurl parses a curl command into options.
+// It supports the -X, -H, -d, --data-raw, --data-binary, --data-urlencode, --json, -u, -b, -F, -A, -e, -G, and -I flags.
+// File references such as -d @file and -F name=@path are rejected, so a command never reads local files.
+func FromCurl(command string) ([]option.Option[*http.Request], error) {
+	args, err := shellSplit(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("%w: must start with curl", ErrInvalidCurl)
+	}
+
+	var (
+		method, url      string
+		get, head        bool
+		compressed       bool
+		headers          []option.Option[*http.Request]
+		auth             []option.Option[*http.Request]
+		data             []string
+		parts            []MultipartPart
+		hasContentType   bool
+		defaultMediaType string
+	)
+
+	for i := 1; i < len(args); i++ {
+		flag, value, hasValue := args[i], "", false
+		if !strings.HasPrefix(flag, "-") || flag == "-" {
+			url = flag
+			continue
+		}
+
+		if !strings.HasPrefix(flag, "--") {
+			// Short flags may be combined, e.g. "-sSL", or have their argument attached, e.g. "-XPOST".
+			for len(flag) > 2 {
+				long, ok := curlFlags[flag[:2]]
+				if !ok {
+					return nil, fmt.Errorf("%w: unsupported flag %s", ErrInvalidCurl, flag[:2])
+				}
+
+				if curlArgFlags[long] {
+					value, hasValue = flag[2:], true
+					flag = flag[:2]
+					break
+				}
+
+				switch long {
+				case "--get":
+					get = true
+				case "--head":
+					head = true
+				}
+
+				flag = "-" + flag[2:]
+			}
+
+			long, ok := curlFlags[flag]
+			if !ok {
+				return nil, fmt.Errorf("%w: unsupported flag %s", ErrInvalidCurl, flag)
+			}
+
+			flag = long
+		}
+
+		if curlArgFlags[flag] && !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%w: flag %s requires an argument", ErrInvalidCurl, flag)
+			}
+
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "--request":
+			method = value
+
+		case "--url":
+			url = value
+
+		case "--header":
+			key, headerValue, found := strings.Cut(value, ":")
+			if !found {
+				return nil, fmt.Errorf("%w: invalid header %q", ErrInvalidCurl, value)
+			}
+
+			key, headerValue = strings.TrimSpace(key), strings.TrimSpace(headerValue)
+			if strings.EqualFold(key, "Content-Type") {
+				hasContentType = true
+			}
+
+			headers = append(headers, WithHeader(key, headerValue))
+
+		case "--user-agent":
+			headers = append(headers, WithUserAgentHeader(value))
+
+		case "--referer":
+			headers = append(headers, WithRefererHeader(value))
+
+		case "--user":
+			username, password, _ := strings.Cut(value, ":")
+			auth = append(auth, WithBasicAuth(username, password))
+
+		case "--cookie":
+			if !strings.Contains(value, "=") {
+				return nil, fmt.Errorf("%w: cookie files are not supported", ErrInvalidCurl)
+			}
+
+			for _, cookie := range strings.Split(value, ";") {
+				name, cookieValue, _ := strings.Cut(strings.TrimSpace(cookie), "=")
+				if name != "" {
+					auth = append(auth, WithCookie(&http.Cookie{Name: name, Value: cookieValue}))
+				}
+			}
+
+		case "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				return nil, fmt.Errorf("%w: data files are not supported", ErrInvalidCurl)
+			}
+
+			data = append(data, value)
+			defaultMediaType = "application/x-www-form-urlencoded"
+
+		case "--data-raw":
+			data = append(data, value)
+			defaultMediaType = "application/x-www-form-urlencoded"
+
+		case "--json":
+			data = append(data, value)
+			defaultMediaType = "application/json"
+			headers = append(headers, WithAcceptHeader("application/json"))
+
+		case "--data-urlencode":
+			encoded, err := curlURLEncode(value)
+			if err != nil {
+				return nil, err
+			}
+
+			data = append(data, encoded)
+			defaultMediaType = "application/x-www-form-urlencoded"
+
+		case "--form":
+			part, err := curlFormPart(value)
+			if err != nil {
+				return nil, err
+			}
+
+			parts = append(parts, part)
+
+		case "--get":
+			get = true
+
+		case "--head":
+			head = true
+
+		case "--compressed":
+			compressed = true
+
+		default:
+			if !curlIgnoredFlags[flag] {
+				return nil, fmt.Errorf("%w: unsupported flag %s", ErrInvalidCurl, flag)
+			}
+		}
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("%w: missing URL", ErrInvalidCurl)
+	}
+
+	if len(data) > 0 && len(parts) > 0 {
+		return nil, fmt.Errorf("%w: can't combine data and form flags", ErrInvalidCurl)
+	}
+
+	defaultMethod := http.MethodGet
+	switch {
+	case head:
+		defaultMethod = http.MethodHead
+	case (len(data) > 0 && !get) || len(parts) > 0:
+		defaultMethod = http.MethodPost
+	}
+
+	if method == "" {
+		method = defaultMethod
+	}
+
+	options := []option.Option[*http.Request]{WithMethod(method), withCurlURL(url)}
+	options = append(options, headers...)
+	options = append(options, auth...)
+
+	body := strings.Join(data, "&")
+	switch {
+	case len(data) > 0 && get:
+		options = append(options, withRawQuery(body))
+
+	case len(data) > 0:
+		if !hasContentType {
+			options = append(options, WithContentTypeHeader(defaultMediaType))
+		}
+
+		options = append(options, WithBodyString(body))
+
+	case len(parts) > 0:
+		options = append(options, WithBodyMultipart(parts...))
+	}
+
+	if compressed {
+		options = append(options, WithDecompression(nil))
+	}
+
+	return options, nil
+}
+
+// withCurlURL applies a URL string to the *http.Request, along with its host.
+func withCurlURL(url string) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		request, err := WithURL(url).Apply(request)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Host = request.URL.Host
+		return request, nil
+	})
+}
+
+// withRawQuery appends an encoded query string to the *http.Request URL.
+func withRawQuery(query string) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		if request.URL.RawQuery == "" {
+			request.URL.RawQuery = query
+		} else {
+			request.URL.RawQuery = fmt.Sprintf("%s&%s", request.URL.RawQuery, query)
+		}
+
+		return request, nil
+	})
+}
+
+// curlURLEncode encodes a --data-urlencode argument, which is one of "content", "=content", or "name=content".
+func curlURLEncode(value string) (string, error) {
+	name, content, found := strings.Cut(value, "=")
+	if !found {
+		if strings.Contains(value, "@") {
+			return "", fmt.Errorf("%w: data files are not supported", ErrInvalidCurl)
+		}
+
+		return pkgurl.QueryEscape(value), nil
+	}
+
+	if name == "" {
+		return pkgurl.QueryEscape(content), nil
+	}
+
+	return fmt.Sprintf("%s=%s", name, pkgurl.QueryEscape(content)), nil
+}
+
+// curlFormPart parses a --form argument of the form "name=value".
+// File references ("name=@path" and "name=<path") are not supported, the same as for --data.
+func curlFormPart(value string) (MultipartPart, error) {
+	name, content, found := strings.Cut(value, "=")
+	if !found {
+		return MultipartPart{}, fmt.Errorf("%w: invalid form %q", ErrInvalidCurl, value)
+	}
+
+	if strings.HasPrefix(content, "@") || strings.HasPrefix(content, "<") {
+		return MultipartPart{}, fmt.Errorf("%w: form files are not supported", ErrInvalidCurl)
+	}
+
+	return MultipartField(name, content), nil
+}
+
+// shellSplit splits a command into arguments following POSIX shell quoting rules, including bash's $'...' strings.
+func shellSplit(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+	)
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+
+		case c == '\\':
+			if i+1 >= len(command) {
+				return nil, fmt.Errorf("%w: trailing backslash", ErrInvalidCurl)
+			}
+
+			i++
+			if command[i] == '\n' {
+				continue
+			}
+
+			if command[i] == '\r' && i+1 < len(command) && command[i+1] == '\n' {
+				i++
+				continue
+			}
+
+			current.WriteByte(command[i])
+			inArg = true
+
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated single quote", ErrInvalidCurl)
+			}
+
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+
+		case c == '$' && i+1 < len(command) && command[i+1] == '\'':
+			n, err := ansiCQuoted(command[i+2:], &current)
+			if err != nil {
+				return nil, err
+			}
+
+			i += n + 2
+			inArg = true
+
+		case c == '"':
+			n, err := doubleQuoted(command[i+1:], &current)
+			if err != nil {
+				return nil, err
+			}
+
+			i += n + 1
+			inArg = true
+
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// doubleQuoted writes the content of a double-quoted string to b, returning the index of the closing quote in s.
+func doubleQuoted(s string, b *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return i, nil
+
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+				i++
+				if s[i] != '\n' {
+					b.WriteByte(s[i])
+				}
+
+				continue
+			}
+
+			b.WriteByte(c)
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unterminated double quote", ErrInvalidCurl)
+}
+
+// ansiCQuoted writes the content of a $'...' string to b, returning the index of the closing quote in s.
+func ansiCQuoted(s string, b *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', 'a': '\a', 'b': '\b', 'f': '\f', 'v': '\v', 'e': 0x1b, '\\': '\\', '\'': '\'', '"': '"', '?': '?'}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' {
+			return i, nil
+		}
+
+		if c != '\\' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if escaped, ok := escapes[s[i]]; ok {
+			b.WriteByte(escaped)
+			continue
+		}
+
+		if s[i] == 'x' || s[i] == 'u' || s[i] == 'U' {
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+			end := i + 1
+			for end < len(s) && end < i+1+digits && isHexDigit(s[end]) {
+				end++
+			}
+
+			if end == i+1 {
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+				continue
+			}
+
+			code, _ := strconv.ParseUint(s[i+1:end], 16, 32)
+			if s[i] == 'x' {
+				b.WriteByte(byte(code))
+			} else {
+				b.WriteRune(rune(code))
+			}
+
+			i = end - 1
+			continue
+		}
+
+		b.WriteByte('\\')
+		b.WriteByte(s[i])
+	}
+
+	return 0, fmt.Errorf("%w: unterminated $' quote", ErrInvalidCurl)
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
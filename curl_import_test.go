@@ -0,0 +1,183 @@
+package qst_test
+
+import (
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromCurl(t *testing.T) {
+	t.Run("devtools command", func(t *testing.T) {
+		request, err := qst.NewFromCurl(`curl 'https://breakfast.com/api/cereals?page=2' \
+  -H 'accept: application/json' \
+  -H $'x-note: it\'s\tgrrreat' \
+  -b 'session=1234; cookie-crisp=chocolate' \
+  --data-raw '{"name":"Life"}' \
+  --compressed`)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodPost, request.Method)
+		assert.Equal(t, "https://breakfast.com/api/cereals?page=2", request.URL.String())
+		assert.Equal(t, "breakfast.com", request.Host)
+		assert.Equal(t, "application/json", request.Header.Get("Accept"))
+		assert.Equal(t, "it's\tgrrreat", request.Header.Get("X-Note"))
+		assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
+		assert.Equal(t, `{"name":"Life"}`, readAll(t, request.Body))
+
+		cookie, err := request.Cookie("cookie-crisp")
+		require.NoError(t, err)
+		assert.Equal(t, "chocolate", cookie.Value)
+	})
+
+	t.Run("method, basic auth, and combined flags", func(t *testing.T) {
+		request, err := qst.NewFromCurl(`curl -sSL -XPUT -u "TonyTheTiger:grrreat" -A qst -e https://breakfast.com --url https://breakfast.com/api/cereals/1234 -H "Content-Type: application/json" -d "{\"name\": \"Frosted Flakes\"}"`)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodPut, request.Method)
+		assert.Equal(t, "https://breakfast.com/api/cereals/1234", request.URL.String())
+		assert.Equal(t, "qst", request.Header.Get("User-Agent"))
+		assert.Equal(t, "https://breakfast.com", request.Header.Get("Referer"))
+		assert.Equal(t, []string{"application/json"}, request.Header.Values("Content-Type"))
+		assert.Equal(t, `{"name": "Frosted Flakes"}`, readAll(t, request.Body))
+
+		username, password, ok := request.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "TonyTheTiger", username)
+		assert.Equal(t, "grrreat", password)
+	})
+
+	t.Run("data-urlencode", func(t *testing.T) {
+		request, err := qst.NewFromCurl(`curl https://breakfast.com/api/cereals --data-urlencode 'name=Cap'\''n Crunch' --data-urlencode '=&' -d page=2`)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodPost, request.Method)
+		assert.Equal(t, "name=Cap%27n+Crunch&%26&page=2", readAll(t, request.Body))
+	})
+
+	t.Run("get", func(t *testing.T) {
+		request, err := qst.NewFromCurl(`curl -G https://breakfast.com/api/cereals?page=2 -d limit=10`)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodGet, request.Method)
+		assert.Equal(t, "page=2&limit=10", request.URL.RawQuery)
+		assert.Nil(t, request.Body)
+	})
+
+	t.Run("head", func(t *testing.T) {
+		request, err := qst.NewFromCurl(`curl -I https://breakfast.com/api/cereals`)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodHead, request.Method)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		request, err := qst.NewFromCurl(`curl https://breakfast.com/api/cereals --json '{"name":"Life"}'`)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodPost, request.Method)
+		assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+		assert.Equal(t, "application/json", request.Header.Get("Accept"))
+	})
+
+	t.Run("form", func(t *testing.T) {
+		request, err := qst.NewFromCurl(`curl https://breakfast.com/api/cereals -F name=Life -F grain=oats`)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, request.Method)
+
+		_, params, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+		require.NoError(t, err)
+
+		form, err := multipart.NewReader(request.Body, params["boundary"]).ReadForm(1 << 20)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Life"}, form.Value["name"])
+		assert.Equal(t, []string{"oats"}, form.Value["grain"])
+	})
+
+	t.Run("data keeps newlines", func(t *testing.T) {
+		request, err := qst.NewFromCurl("curl https://breakfast.com/api/cereals -d $'name=Life\\r\\n'")
+		require.NoError(t, err)
+		assert.Equal(t, "name=Life\r\n", readAll(t, request.Body))
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "gzip, deflate", r.Header.Get("Accept-Encoding"))
+			w.Header().Set("Content-Encoding", "deflate")
+			w.Write(encode(t, "deflate", "Life"))
+		}))
+		defer server.Close()
+
+		options, err := qst.FromCurl(fmt.Sprintf("curl %s --compressed", server.URL))
+		require.NoError(t, err)
+
+		response, err := qst.Get("", options...)
+		require.NoError(t, err)
+		assert.Equal(t, "Life", readAll(t, response.Body))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testCases := map[string]string{
+			"not curl":            `wget https://breakfast.com`,
+			"missing URL":         `curl -H 'Accept: application/json'`,
+			"missing argument":    `curl https://breakfast.com -H`,
+			"unsupported flag":    `curl https://breakfast.com --proxy http://proxy`,
+			"unterminated quote":  `curl 'https://breakfast.com`,
+			"data file":           `curl https://breakfast.com -d @body.json`,
+			"cookie file":         `curl https://breakfast.com -b cookies.txt`,
+			"form file":           `curl https://breakfast.com -F box=@box.png`,
+			"form file contents":  `curl https://breakfast.com -F 'name=<name.txt'`,
+			"invalid header":      `curl https://breakfast.com -H grain`,
+			"data and form":       `curl https://breakfast.com -d a=b -F c=d`,
+			"trailing backslash":  `curl https://breakfast.com \`,
+			"unterminated double": `curl "https://breakfast.com`,
+		}
+
+		for name, command := range testCases {
+			t.Run(name, func(t *testing.T) {
+				_, err := qst.FromCurl(command)
+				assert.ErrorIs(t, err, qst.ErrInvalidCurl)
+			})
+		}
+	})
+}
+
+func TestFromCurl_roundTrip(t *testing.T) {
+	request, err := qst.NewPost("https://breakfast.com/api/cereals",
+		qst.WithQuery("name", "Cap'n Crunch"),
+		qst.WithBasicAuth("TonyTheTiger", "grrreat"),
+		qst.WithCookie(&http.Cookie{Name: "session", Value: "1234"}),
+		qst.WithBodyJSON(map[string]string{"name": "Cap'n Crunch"}),
+	)
+	require.NoError(t, err)
+
+	command, err := qst.ToCurl(request)
+	require.NoError(t, err)
+
+	imported, err := qst.NewFromCurl(command)
+	require.NoError(t, err)
+
+	assert.Equal(t, request.Method, imported.Method)
+	assert.Equal(t, request.URL.String(), imported.URL.String())
+	assert.Equal(t, request.Header, imported.Header)
+	assert.Equal(t, readAll(t, request.Body), readAll(t, imported.Body))
+}
+
+func ExampleFromCurl() {
+	options, _ := qst.FromCurl(`curl -X PATCH https://breakfast.com/api/cereals/1234 -H 'Authorization: Bearer c0rnfl@k3s'`)
+	request, _ := qst.New(http.MethodGet, "", options...)
+
+	fmt.Println(request.Method)
+	fmt.Println(request.URL)
+	fmt.Println(request.Header.Get("Authorization"))
+
+	// Output:
+	// PATCH
+	// https://breakfast.com/api/cereals/1234
+	// Bearer c0rnfl@k3s
+}
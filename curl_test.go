@@ -0,0 +1,137 @@
+package qst_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/broothie/option"
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToCurl(t *testing.T) {
+	testCases := map[string]struct {
+		method   string
+		options  []option.Option[*http.Request]
+		expected string
+		redacted string
+	}{
+		"get": {
+			method:   http.MethodGet,
+			expected: "curl https://breakfast.com/api/cereals",
+		},
+		"head": {
+			method:   http.MethodHead,
+			expected: "curl --head https://breakfast.com/api/cereals",
+		},
+		"query": {
+			method:   http.MethodGet,
+			options:  []option.Option[*http.Request]{qst.WithQuery("name", "Cap'n Crunch")},
+			expected: `curl 'https://breakfast.com/api/cereals?name=Cap%27n+Crunch'`,
+		},
+		"headers": {
+			method:   http.MethodDelete,
+			options:  []option.Option[*http.Request]{qst.WithHeader("X-Grain", "oats"), qst.WithBearerAuth("c0rnfl@k3s")},
+			expected: "curl -X DELETE https://breakfast.com/api/cereals -H 'Authorization: Bearer c0rnfl@k3s' -H 'X-Grain: oats'",
+			redacted: "curl -X DELETE https://breakfast.com/api/cereals -H 'Authorization: Bearer REDACTED' -H 'X-Grain: oats'",
+		},
+		"basic auth": {
+			method:   http.MethodGet,
+			options:  []option.Option[*http.Request]{qst.WithBasicAuth("TonyTheTiger", "grrreat")},
+			expected: "curl https://breakfast.com/api/cereals -u TonyTheTiger:grrreat",
+			redacted: "curl https://breakfast.com/api/cereals -u TonyTheTiger:REDACTED",
+		},
+		"user password": {
+			method:   http.MethodGet,
+			options:  []option.Option[*http.Request]{qst.WithUserPassword("TonyTheTiger", "grrreat")},
+			expected: "curl https://breakfast.com/api/cereals -u TonyTheTiger:grrreat",
+			redacted: "curl https://breakfast.com/api/cereals -u TonyTheTiger:REDACTED",
+		},
+		"cookies": {
+			method: http.MethodGet,
+			options: []option.Option[*http.Request]{
+				qst.WithCookie(&http.Cookie{Name: "cookie-crisp", Value: "chocolate"}),
+				qst.WithCookie(&http.Cookie{Name: "session", Value: "1234"}),
+			},
+			expected: "curl https://breakfast.com/api/cereals -b 'cookie-crisp=chocolate; session=1234'",
+			redacted: "curl https://breakfast.com/api/cereals -b 'cookie-crisp=REDACTED; session=REDACTED'",
+		},
+		"body": {
+			method:   http.MethodPost,
+			options:  []option.Option[*http.Request]{qst.WithBodyJSON(map[string]string{"name": "Cap'n Crunch"})},
+			expected: `curl -X POST https://breakfast.com/api/cereals -H 'Content-Type: application/json' --data-binary '{"name":"Cap'\''n Crunch"}` + "\n'",
+		},
+		"get with body": {
+			method:   http.MethodGet,
+			options:  []option.Option[*http.Request]{qst.WithBodyString("oats")},
+			expected: "curl -X GET https://breakfast.com/api/cereals --data-binary oats",
+		},
+		"head with body": {
+			method:   http.MethodHead,
+			options:  []option.Option[*http.Request]{qst.WithBodyString("oats")},
+			expected: "curl -X HEAD https://breakfast.com/api/cereals --data-binary oats",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			request, err := qst.New(tc.method, "https://breakfast.com/api/cereals", tc.options...)
+			require.NoError(t, err)
+
+			command, err := qst.ToCurl(request)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, command)
+
+			if tc.redacted == "" {
+				tc.redacted = tc.expected
+			}
+
+			command, err = qst.ToCurlRedacted(request)
+			require.NoError(t, err)
+			assert.Equal(t, tc.redacted, command)
+		})
+	}
+
+	t.Run("unreplayable body is restored", func(t *testing.T) {
+		request, err := qst.NewPut("https://breakfast.com/api/cereals",
+			qst.WithBody(ioutil.NopCloser(bytes.NewBufferString("Part of a complete breakfast."))),
+		)
+		require.NoError(t, err)
+
+		command, err := qst.ToCurl(request)
+		require.NoError(t, err)
+		assert.Equal(t, "curl -X PUT https://breakfast.com/api/cereals --data-binary 'Part of a complete breakfast.'", command)
+		assert.Equal(t, "Part of a complete breakfast.", readAll(t, request.Body))
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		request, err := qst.NewPut("https://breakfast.com/api/cereals", qst.WithBodyReader(broken{}))
+		require.NoError(t, err)
+
+		_, err = qst.ToCurl(request)
+		assert.EqualError(t, err, "broken")
+	})
+}
+
+func TestWithCurlDump(t *testing.T) {
+	t.Run("write error", func(t *testing.T) {
+		_, err := qst.NewGet("https://breakfast.com/api/cereals", qst.WithCurlDump(broken{}))
+		assert.EqualError(t, err, "failed to apply option 0: broken")
+	})
+}
+
+func ExampleWithCurlDump() {
+	var buffer bytes.Buffer
+	qst.NewPost("https://breakfast.com/api/cereals",
+		qst.WithBearerAuth("c0rnfl@k3s"),
+		qst.WithBodyString("Part of a complete breakfast."),
+		qst.WithRedactedCurlDump(&buffer),
+	)
+
+	fmt.Print(buffer.String())
+	// Output: curl -X POST https://breakfast.com/api/cereals -H 'Authorization: Bearer REDACTED' --data-binary 'Part of a complete breakfast.'
+}
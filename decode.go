@@ -0,0 +1,90 @@
+package qst
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/broothie/option"
+)
+
+// MaxDecodeSize is the maximum number of response body bytes read by the decoding helpers.
+var MaxDecodeSize int64 = 10 << 20
+
+var (
+	// ErrUnexpectedContentType is returned when a response's Content-Type doesn't match the decoder.
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+
+	// ErrResponseTooLarge is returned when a response body exceeds MaxDecodeSize.
+	ErrResponseTooLarge = errors.New("response body too large")
+)
+
+// DoJSON makes an *http.Request using the global client and decodes the JSON response body into a T.
+func DoJSON[T any](method, url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DecodeJSON[T](Do(method, url, options...))
+}
+
+// DoXML makes an *http.Request using the global client and decodes the XML response body into a T.
+func DoXML[T any](method, url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DecodeXML[T](Do(method, url, options...))
+}
+
+// DecodeJSON decodes the JSON body of response into a T and closes it.
+// It accepts the results of a request function directly, e.g. DecodeJSON[T](client.Get(url)).
+func DecodeJSON[T any](response *http.Response, err error) (T, *http.Response, error) {
+	return decode[T](response, err, isJSONMediaType, json.Unmarshal)
+}
+
+// DecodeXML decodes the XML body of response into a T and closes it.
+// It accepts the results of a request function directly, e.g. DecodeXML[T](client.Get(url)).
+func DecodeXML[T any](response *http.Response, err error) (T, *http.Response, error) {
+	return decode[T](response, err, isXMLMediaType, xml.Unmarshal)
+}
+
+func decode[T any](response *http.Response, err error, acceptMediaType func(string) bool, unmarshal func([]byte, interface{}) error) (T, *http.Response, error) {
+	var v T
+	if err != nil {
+		return v, response, err
+	}
+
+	defer response.Body.Close()
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return v, response, err
+		}
+
+		if !acceptMediaType(mediaType) {
+			return v, response, fmt.Errorf("%w %q", ErrUnexpectedContentType, mediaType)
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, MaxDecodeSize+1))
+	if err != nil {
+		return v, response, err
+	}
+
+	if int64(len(body)) > MaxDecodeSize {
+		return v, response, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, MaxDecodeSize)
+	}
+
+	if err := unmarshal(body, &v); err != nil {
+		return v, response, err
+	}
+
+	return v, response, nil
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXMLMediaType(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,104 @@
+package qst_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cereal struct {
+	XMLName xml.Name `json:"-" xml:"cereal"`
+	Name    string   `json:"name" xml:"name"`
+	Raisins bool     `json:"raisins" xml:"raisins"`
+}
+
+func TestDoJSON(t *testing.T) {
+	t.Run("decodes", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `{"name":"Raisin Bran","raisins":true}`)
+		}))
+		defer server.Close()
+
+		got, response, err := qst.GetJSON[cereal](server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, cereal{Name: "Raisin Bran", Raisins: true}, got)
+	})
+
+	t.Run("accepts +json media types", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/vnd.breakfast+json")
+			fmt.Fprint(w, `{"name":"Life"}`)
+		}))
+		defer server.Close()
+
+		got, _, err := qst.DoJSON[cereal](http.MethodGet, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "Life", got.Name)
+	})
+
+	t.Run("unexpected content type", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html></html>")
+		}))
+		defer server.Close()
+
+		_, _, err := qst.GetJSON[cereal](server.URL)
+		assert.ErrorIs(t, err, qst.ErrUnexpectedContentType)
+		assert.EqualError(t, err, `unexpected content type "text/html"`)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		defer func(size int64) { qst.MaxDecodeSize = size }(qst.MaxDecodeSize)
+		qst.MaxDecodeSize = 8
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"Honey Bunches of Oats"}`)
+		}))
+		defer server.Close()
+
+		_, _, err := qst.GetJSON[cereal](server.URL)
+		assert.ErrorIs(t, err, qst.ErrResponseTooLarge)
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		_, response, err := qst.GetJSON[cereal]("%")
+		assert.Nil(t, response)
+		assert.EqualError(t, err, `parse "%": invalid URL escape "%"`)
+	})
+}
+
+func TestDoXML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<cereal><name>Grape Nuts</name><raisins>false</raisins></cereal>`)
+	}))
+	defer server.Close()
+
+	got, _, err := qst.PostXML[cereal](server.URL, qst.WithBodyXML(cereal{Name: "Grape Nuts"}))
+	require.NoError(t, err)
+	assert.Equal(t, "Grape Nuts", got.Name)
+}
+
+func ExampleDecodeJSON() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"Rice Krispies"}`)
+	}))
+	defer server.Close()
+
+	client := qst.NewClient(server.Client(), server.URL)
+	cereal, _, _ := qst.DecodeJSON[map[string]string](client.Get("/cereals/1234"))
+
+	fmt.Println(cereal["name"])
+	// Output: Rice Krispies
+}
@@ -0,0 +1,183 @@
+package qst
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/broothie/option"
+)
+
+// Decoder returns a reader of the decoded content of r, a body with a content coding.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// Decompressor advertises content codings in the "Accept-Encoding" header of requests and decodes responses which use them.
+// gzip and deflate are supported by default. Other codings, such as zstd and br, can be added with Register.
+type Decompressor struct {
+	mutex    sync.RWMutex
+	codings  []string
+	decoders map[string]Decoder
+}
+
+// NewDecompressor returns a new *Decompressor which supports gzip and deflate.
+func NewDecompressor() *Decompressor {
+	decompressor := &Decompressor{decoders: make(map[string]Decoder)}
+	decompressor.Register("gzip", decodeGzip)
+	decompressor.Register("deflate", decodeDeflate)
+	return decompressor
+}
+
+// Register adds decoder for coding, e.g. "br", replacing any already registered for coding.
+func (d *Decompressor) Register(coding string, decoder Decoder) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	coding = strings.ToLower(coding)
+	if _, ok := d.decoders[coding]; !ok {
+		d.codings = append(d.codings, coding)
+	}
+
+	d.decoders[coding] = decoder
+}
+
+// WithDecompression decodes the response to the *http.Request with decompressor. If decompressor is nil, a new one
+// supporting gzip and deflate is used.
+func WithDecompression(decompressor *Decompressor) option.Option[*http.Request] {
+	if decompressor == nil {
+		decompressor = NewDecompressor()
+	}
+
+	return WithMiddleware(decompressor.RoundTripper)
+}
+
+// RoundTripper wraps next, setting the "Accept-Encoding" header of requests which don't have one, and decoding responses.
+// Responses are decoded even when the request's "Accept-Encoding" header was set by the caller, in which case
+// net/http doesn't decode them. Responses with codings which aren't registered are returned as is.
+func (d *Decompressor) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		if request.Header.Get("Accept-Encoding") == "" {
+			d.mutex.RLock()
+			acceptEncoding := strings.Join(d.codings, ", ")
+			d.mutex.RUnlock()
+
+			request = request.Clone(request.Context())
+			request.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+
+		response, err := next.RoundTrip(request)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := d.decode(request, response); err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+
+		return response, nil
+	})
+}
+
+// decode replaces the body of response with its decoded content, if all of its codings are registered.
+func (d *Decompressor) decode(request *http.Request, response *http.Response) error {
+	codings := contentCodings(response.Header)
+	if len(codings) == 0 || request.Method == http.MethodHead || response.Body == nil || response.Body == http.NoBody {
+		return nil
+	}
+
+	decoders := make([]Decoder, len(codings))
+	d.mutex.RLock()
+	for i, coding := range codings {
+		decoder, ok := d.decoders[coding]
+		if !ok {
+			d.mutex.RUnlock()
+			return nil
+		}
+
+		decoders[i] = decoder
+	}
+	d.mutex.RUnlock()
+
+	body := &decodedBody{Reader: response.Body, closers: []io.Closer{response.Body}}
+	for i := len(decoders) - 1; i >= 0; i-- {
+		decoded, err := decoders[i](body.Reader)
+		if errors.Is(err, io.EOF) {
+			decoded, err = ioutil.NopCloser(strings.NewReader("")), nil
+		}
+
+		if err != nil {
+			body.Close()
+			return fmt.Errorf("failed to decode %s response body: %w", codings[i], err)
+		}
+
+		body.Reader = decoded
+		body.closers = append(body.closers, decoded)
+	}
+
+	response.Body = body
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return nil
+}
+
+// contentCodings returns the content codings of the "Content-Encoding" header in the order they were applied,
+// ignoring "identity".
+func contentCodings(header http.Header) []string {
+	var codings []string
+	for _, value := range header.Values("Content-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" && coding != "identity" {
+				codings = append(codings, coding)
+			}
+		}
+	}
+
+	return codings
+}
+
+// decodedBody reads decoded content, closing each decoder and the original body when closed.
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if closeErr := b.closers[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func decodeGzip(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// decodeDeflate decodes zlib, which the deflate coding is, falling back to raw deflate, which some servers send instead.
+func decodeDeflate(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil && len(header) == 0 {
+		return nil, err
+	}
+
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+
+	return flate.NewReader(buffered), nil
+}
@@ -0,0 +1,117 @@
+package qst_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, coding, content string) []byte {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch coding {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "deflate":
+		writer = zlib.NewWriter(&buffer)
+	case "raw-deflate":
+		var err error
+		writer, err = flate.NewWriter(&buffer, flate.DefaultCompression)
+		require.NoError(t, err)
+	case "base64":
+		writer = base64.NewEncoder(base64.StdEncoding, &buffer)
+	}
+
+	_, err := io.WriteString(writer, content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestDecompressor(t *testing.T) {
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		coding := r.URL.Query().Get("coding")
+		if coding == "" {
+			return
+		}
+
+		contentEncoding := coding
+		if coding == "raw-deflate" {
+			contentEncoding = "deflate"
+		}
+
+		w.Header().Set("Content-Encoding", contentEncoding)
+		if r.URL.Query().Get("empty") == "" {
+			w.Write(encode(t, coding, "Life"))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("advertises and decodes gzip and deflate", func(t *testing.T) {
+		for _, coding := range []string{"gzip", "deflate", "raw-deflate"} {
+			t.Run(coding, func(t *testing.T) {
+				response, err := qst.Get(server.URL, qst.WithDecompression(nil), qst.WithQuery("coding", coding))
+				require.NoError(t, err)
+				assert.Equal(t, "gzip, deflate", acceptEncoding)
+				assert.Equal(t, "Life", readAll(t, response.Body))
+				assert.Empty(t, response.Header.Get("Content-Encoding"))
+				assert.True(t, response.Uncompressed)
+			})
+		}
+	})
+
+	t.Run("registered decoders", func(t *testing.T) {
+		decompressor := qst.NewDecompressor()
+		decompressor.Register("base64", func(r io.Reader) (io.ReadCloser, error) {
+			return ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+		})
+
+		response, err := qst.Get(server.URL, qst.WithDecompression(decompressor), qst.WithQuery("coding", "base64"))
+		require.NoError(t, err)
+		assert.Equal(t, "gzip, deflate, base64", acceptEncoding)
+		assert.Equal(t, "Life", readAll(t, response.Body))
+	})
+
+	t.Run("caller set Accept-Encoding", func(t *testing.T) {
+		response, err := qst.Get(server.URL,
+			qst.WithDecompression(nil),
+			qst.WithHeader("Accept-Encoding", "gzip"),
+			qst.WithQuery("coding", "gzip"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", acceptEncoding)
+		assert.Equal(t, "Life", readAll(t, response.Body))
+	})
+
+	t.Run("unregistered coding", func(t *testing.T) {
+		response, err := qst.Get(server.URL, qst.WithDecompression(nil), qst.WithQuery("coding", "base64"))
+		require.NoError(t, err)
+		assert.Equal(t, "base64", response.Header.Get("Content-Encoding"))
+		assert.Equal(t, string(encode(t, "base64", "Life")), readAll(t, response.Body))
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		response, err := qst.Get(server.URL, qst.WithDecompression(nil), qst.WithQuery("coding", "gzip"), qst.WithQuery("empty", "true"))
+		require.NoError(t, err)
+		assert.Empty(t, readAll(t, response.Body))
+	})
+
+	t.Run("HEAD", func(t *testing.T) {
+		response, err := qst.Head(server.URL, qst.WithDecompression(nil), qst.WithQuery("coding", "gzip"))
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", response.Header.Get("Content-Encoding"))
+	})
+}
@@ -0,0 +1,332 @@
+package qst
+
+import (
+	"crypto/md5" // #nosec G501 -- MD5 is required by RFC 7616 for compatibility.
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/broothie/option"
+)
+
+// digestHashes are the supported Digest authentication algorithms, from strongest to weakest.
+var digestHashes = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"SHA-256", sha256.New},
+	{"MD5", md5.New},
+}
+
+// DigestAuth authenticates requests with HTTP Digest authentication (RFC 7616).
+// Requests which are rejected with a Digest challenge are retried once with credentials, provided their body can be
+// replayed. The challenge is then reused for subsequent requests, so a DigestAuth should be shared, e.g. with
+// Client.Middleware.
+type DigestAuth struct {
+	Username string
+	Password string
+
+	// NewCnonce returns client nonces. If nil, random ones are used.
+	NewCnonce func() string
+
+	mutex      sync.Mutex
+	challenge  *digestChallenge
+	nonceCount int
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	userhash  bool
+	stale     bool
+	newHash   func() hash.Hash
+	session   bool
+}
+
+// WithDigestAuth authenticates the *http.Request with HTTP Digest authentication. See DigestAuth.
+func WithDigestAuth(username, password string) option.Option[*http.Request] {
+	auth := &DigestAuth{Username: username, Password: password}
+	return WithMiddleware(auth.RoundTripper)
+}
+
+// RoundTripper wraps next, answering Digest challenges and authorizing subsequent requests with the last challenge.
+func (d *DigestAuth) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		attempt := request
+		used := d.currentChallenge()
+		if used != nil {
+			authorized, err := d.authorize(request, used)
+			if err != nil {
+				return nil, err
+			}
+
+			attempt = authorized
+		}
+
+		response, err := next.RoundTrip(attempt)
+		if err != nil || response.StatusCode != http.StatusUnauthorized {
+			return response, err
+		}
+
+		// A new challenge is answered, unless it's the one which was just rejected, meaning the credentials are wrong.
+		// Either way, the rejected challenge isn't used again.
+		challenge := parseDigestChallenge(response.Header.Values("WWW-Authenticate"))
+		if challenge == nil || (used != nil && !challenge.stale && challenge.nonce == used.nonce && challenge.realm == used.realm) {
+			d.clearChallenge(used)
+			return response, nil
+		}
+
+		d.setChallenge(challenge)
+		if !isRewindable(request) {
+			return response, nil
+		}
+
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+
+		retry, err := rewindRequest(request, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		if retry, err = d.authorize(retry, challenge); err != nil {
+			return nil, err
+		}
+
+		response, err = next.RoundTrip(retry)
+		if err == nil && response.StatusCode == http.StatusUnauthorized {
+			d.clearChallenge(challenge)
+		}
+
+		return response, err
+	})
+}
+
+func (d *DigestAuth) currentChallenge() *digestChallenge {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.challenge
+}
+
+func (d *DigestAuth) setChallenge(challenge *digestChallenge) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.challenge = challenge
+	d.nonceCount = 0
+}
+
+// clearChallenge forgets challenge, if it's still the current one, so that the next request is challenged afresh.
+func (d *DigestAuth) clearChallenge(challenge *digestChallenge) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if challenge != nil && d.challenge == challenge {
+		d.challenge = nil
+		d.nonceCount = 0
+	}
+}
+
+// nextNonceCount returns the next nonce count for challenge.
+func (d *DigestAuth) nextNonceCount(challenge *digestChallenge) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.challenge != challenge {
+		d.challenge = challenge
+		d.nonceCount = 0
+	}
+
+	d.nonceCount++
+	return d.nonceCount
+}
+
+// authorize returns a copy of request with an "Authorization" header answering challenge.
+func (d *DigestAuth) authorize(request *http.Request, challenge *digestChallenge) (*http.Request, error) {
+	hashHex := func(s string) string {
+		h := challenge.newHash()
+		io.WriteString(h, s)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	cnonce := ""
+	if d.NewCnonce != nil {
+		cnonce = d.NewCnonce()
+	} else {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+
+		cnonce = hex.EncodeToString(random)
+	}
+
+	nonceCount := fmt.Sprintf("%08x", d.nextNonceCount(challenge))
+	uri := request.URL.RequestURI()
+
+	ha1 := hashHex(fmt.Sprintf("%s:%s:%s", d.Username, challenge.realm, d.Password))
+	if challenge.session {
+		ha1 = hashHex(fmt.Sprintf("%s:%s:%s", ha1, challenge.nonce, cnonce))
+	}
+
+	ha2 := hashHex(fmt.Sprintf("%s:%s", request.Method, uri))
+	if challenge.qop == "auth-int" {
+		body, err := peekBody(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body: %w", err)
+		}
+
+		ha2 = hashHex(fmt.Sprintf("%s:%s:%s", request.Method, uri, hashHex(string(body))))
+	}
+
+	var response string
+	if challenge.qop == "" {
+		response = hashHex(fmt.Sprintf("%s:%s:%s", ha1, challenge.nonce, ha2))
+	} else {
+		response = hashHex(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, challenge.nonce, nonceCount, cnonce, challenge.qop, ha2))
+	}
+
+	username := d.Username
+	if challenge.userhash {
+		username = hashHex(fmt.Sprintf("%s:%s", d.Username, challenge.realm))
+	}
+
+	params := []string{
+		fmt.Sprintf("username=%s", quoteDigestParam(username)),
+		fmt.Sprintf("realm=%s", quoteDigestParam(challenge.realm)),
+		fmt.Sprintf("uri=%s", quoteDigestParam(uri)),
+		fmt.Sprintf("algorithm=%s", challenge.algorithm),
+		fmt.Sprintf("nonce=%s", quoteDigestParam(challenge.nonce)),
+	}
+
+	if challenge.qop != "" {
+		params = append(params,
+			fmt.Sprintf("nc=%s", nonceCount),
+			fmt.Sprintf("cnonce=%s", quoteDigestParam(cnonce)),
+			fmt.Sprintf("qop=%s", challenge.qop),
+		)
+	}
+
+	params = append(params, fmt.Sprintf("response=%s", quoteDigestParam(response)))
+	if challenge.opaque != "" {
+		params = append(params, fmt.Sprintf("opaque=%s", quoteDigestParam(challenge.opaque)))
+	}
+
+	if challenge.userhash {
+		params = append(params, "userhash=true")
+	}
+
+	authorized := request.Clone(request.Context())
+	authorized.Header.Set("Authorization", "Digest "+strings.Join(params, ", "))
+	return authorized, nil
+}
+
+// parseDigestChallenge returns the strongest supported Digest challenge of the "WWW-Authenticate" header values.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	bestRank := len(digestHashes)
+	for _, value := range values {
+		if len(value) < 7 || !strings.EqualFold(value[:7], "Digest ") {
+			continue
+		}
+
+		params := parseDigestParams(value[7:])
+		algorithm := params["algorithm"]
+		if algorithm == "" {
+			algorithm = "MD5"
+		}
+
+		baseAlgorithm := strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS")
+		for rank, digestHash := range digestHashes {
+			if digestHash.name != baseAlgorithm || rank >= bestRank {
+				continue
+			}
+
+			qop := ""
+			for _, option := range strings.Split(params["qop"], ",") {
+				switch option = strings.TrimSpace(option); {
+				case option == "auth":
+					qop = option
+				case option == "auth-int" && qop == "":
+					qop = option
+				}
+			}
+
+			bestRank = rank
+			best = &digestChallenge{
+				realm:     params["realm"],
+				nonce:     params["nonce"],
+				opaque:    params["opaque"],
+				algorithm: algorithm,
+				qop:       qop,
+				userhash:  strings.EqualFold(params["userhash"], "true"),
+				stale:     strings.EqualFold(params["stale"], "true"),
+				newHash:   digestHash.new,
+				session:   strings.HasSuffix(strings.ToUpper(algorithm), "-SESS"),
+			}
+		}
+	}
+
+	return best
+}
+
+// parseDigestParams parses comma separated auth-params, e.g. `realm="example", qop="auth,auth-int", stale=true`.
+func parseDigestParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, ", ") {
+		equals := strings.Index(s, "=")
+		if equals < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:equals]))
+		s = strings.TrimLeft(s[equals+1:], " ")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+
+				value.WriteByte(s[i])
+			}
+
+			if i < len(s) {
+				i++
+			}
+
+			s = s[i:]
+		} else {
+			end := strings.Index(s, ",")
+			if end < 0 {
+				end = len(s)
+			}
+
+			value.WriteString(strings.TrimSpace(s[:end]))
+			s = s[end:]
+		}
+
+		params[key] = value.String()
+	}
+
+	return params
+}
+
+// quoteDigestParam returns s as a quoted-string.
+func quoteDigestParam(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
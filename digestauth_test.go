@@ -0,0 +1,239 @@
+package qst_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 7616 section 3.9.1 example values.
+const (
+	digestRealm  = "http-auth@example.org"
+	digestNonce  = "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"
+	digestCnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+	digestOpaque = "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"
+)
+
+var digestParamPattern = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|([^,\s]*))`)
+
+func digestParams(authorization string) map[string]string {
+	params := make(map[string]string)
+	for _, match := range digestParamPattern.FindAllStringSubmatch(strings.TrimPrefix(authorization, "Digest "), -1) {
+		params[match[1]] = match[2] + match[3]
+	}
+
+	return params
+}
+
+// digestServer is a handler which challenges requests without valid Digest credentials for Mufasa.
+type digestServer struct {
+	t              *testing.T
+	challenges     []string
+	authorizations []string
+	nonce          string
+	stale          bool
+}
+
+func (s *digestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authorization := r.Header.Get("Authorization")
+	s.authorizations = append(s.authorizations, authorization)
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(s.t, err)
+
+	params := digestParams(authorization)
+	if authorization == "" || params["nonce"] != s.nonce || params["response"] != s.expectedResponse(r, params, body) {
+		for _, challenge := range s.challenges {
+			if s.stale && authorization != "" {
+				challenge += ", stale=true"
+			}
+
+			w.Header().Add("WWW-Authenticate", challenge)
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+}
+
+func (s *digestServer) expectedResponse(r *http.Request, params map[string]string, body []byte) string {
+	newHash := md5.New
+	if params["algorithm"] == "SHA-256" {
+		newHash = sha256.New
+	}
+
+	hashHex := func(format string, args ...interface{}) string {
+		var h hash.Hash = newHash()
+		fmt.Fprintf(h, format, args...)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	ha2 := hashHex("%s:%s", r.Method, r.URL.RequestURI())
+	if params["qop"] == "auth-int" {
+		ha2 = hashHex("%s:%s:%s", r.Method, r.URL.RequestURI(), hashHex("%s", body))
+	}
+
+	ha1 := hashHex("Mufasa:%s:Circle of Life", digestRealm)
+	return hashHex("%s:%s:%s:%s:%s:%s", ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2)
+}
+
+func TestDigestAuth(t *testing.T) {
+	newCnonce := func() string { return digestCnonce }
+
+	testCases := map[string]struct {
+		challenges []string
+		response   string
+	}{
+		"MD5 (RFC 7616 3.9.1)": {
+			challenges: []string{fmt.Sprintf(`Digest realm="%s", qop="auth, auth-int", algorithm=MD5, nonce="%s", opaque="%s"`, digestRealm, digestNonce, digestOpaque)},
+			response:   "8ca523f5e9506fed4657c9700eebdbec",
+		},
+		"SHA-256 preferred (RFC 7616 3.9.1)": {
+			challenges: []string{
+				fmt.Sprintf(`Digest realm="%s", qop="auth, auth-int", algorithm=SHA-256, nonce="%s", opaque="%s"`, digestRealm, digestNonce, digestOpaque),
+				fmt.Sprintf(`Digest realm="%s", qop="auth, auth-int", algorithm=MD5, nonce="%s", opaque="%s"`, digestRealm, digestNonce, digestOpaque),
+			},
+			response: "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			handler := &digestServer{t: t, challenges: testCase.challenges, nonce: digestNonce}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			auth := &qst.DigestAuth{Username: "Mufasa", Password: "Circle of Life", NewCnonce: newCnonce}
+			client := qst.NewClient(http.DefaultClient, server.URL)
+			client.Middleware = []qst.Middleware{auth.RoundTripper}
+
+			response, err := client.Get("/dir/index.html")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			require.Len(t, handler.authorizations, 2)
+			assert.Empty(t, handler.authorizations[0])
+
+			params := digestParams(handler.authorizations[1])
+			assert.Equal(t, "Mufasa", params["username"])
+			assert.Equal(t, digestRealm, params["realm"])
+			assert.Equal(t, "/dir/index.html", params["uri"])
+			assert.Equal(t, "auth", params["qop"])
+			assert.Equal(t, "00000001", params["nc"])
+			assert.Equal(t, digestOpaque, params["opaque"])
+			assert.Equal(t, testCase.response, params["response"])
+
+			t.Run("reuses the nonce", func(t *testing.T) {
+				response, err := client.Get("/dir/index.html")
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, response.StatusCode)
+				require.Len(t, handler.authorizations, 3)
+				assert.Equal(t, "00000002", digestParams(handler.authorizations[2])["nc"])
+			})
+		})
+	}
+
+	t.Run("auth-int", func(t *testing.T) {
+		handler := &digestServer{
+			t:          t,
+			challenges: []string{fmt.Sprintf(`Digest realm="%s", qop="auth-int", algorithm=SHA-256, nonce="%s"`, digestRealm, digestNonce)},
+			nonce:      digestNonce,
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		response, err := qst.Post(server.URL+"/cereals", qst.WithDigestAuth("Mufasa", "Circle of Life"), qst.WithBodyString("Life"))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		require.Len(t, handler.authorizations, 2)
+		assert.Equal(t, "auth-int", digestParams(handler.authorizations[1])["qop"])
+	})
+
+	t.Run("stale nonce", func(t *testing.T) {
+		handler := &digestServer{
+			t:          t,
+			challenges: []string{fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="%s"`, digestRealm, digestNonce)},
+			nonce:      digestNonce,
+			stale:      true,
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		auth := &qst.DigestAuth{Username: "Mufasa", Password: "Circle of Life"}
+		_, err := qst.Get(server.URL, qst.WithMiddleware(auth.RoundTripper))
+		require.NoError(t, err)
+
+		handler.nonce = "n3w"
+		handler.challenges = []string{fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="n3w"`, digestRealm)}
+		response, err := qst.Get(server.URL, qst.WithMiddleware(auth.RoundTripper))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		require.Len(t, handler.authorizations, 4)
+		assert.Equal(t, "n3w", digestParams(handler.authorizations[3])["nonce"])
+		assert.Equal(t, "00000001", digestParams(handler.authorizations[3])["nc"])
+	})
+
+	t.Run("rotated nonce", func(t *testing.T) {
+		handler := &digestServer{
+			t:          t,
+			challenges: []string{fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="%s"`, digestRealm, digestNonce)},
+			nonce:      digestNonce,
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		auth := &qst.DigestAuth{Username: "Mufasa", Password: "Circle of Life"}
+		client := qst.NewClient(http.DefaultClient, server.URL)
+		client.Middleware = []qst.Middleware{auth.RoundTripper}
+
+		_, err := client.Get("/")
+		require.NoError(t, err)
+
+		handler.nonce = "n3w"
+		handler.challenges = []string{fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="n3w"`, digestRealm)}
+		for i := 0; i < 3; i++ {
+			response, err := client.Get("/")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+		}
+
+		require.Len(t, handler.authorizations, 6)
+		assert.Equal(t, "n3w", digestParams(handler.authorizations[3])["nonce"])
+		assert.Equal(t, "00000003", digestParams(handler.authorizations[5])["nc"])
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		handler := &digestServer{
+			t:          t,
+			challenges: []string{fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="%s"`, digestRealm, digestNonce)},
+			nonce:      digestNonce,
+		}
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		auth := &qst.DigestAuth{Username: "Mufasa", Password: "Hakuna Matata"}
+		response, err := qst.Get(server.URL, qst.WithMiddleware(auth.RoundTripper))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+		assert.Len(t, handler.authorizations, 2)
+
+		t.Run("renegotiates the next request", func(t *testing.T) {
+			auth.Password = "Circle of Life"
+			response, err := qst.Get(server.URL, qst.WithMiddleware(auth.RoundTripper))
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			require.Len(t, handler.authorizations, 4)
+			assert.Empty(t, handler.authorizations[2])
+		})
+	})
+}
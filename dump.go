@@ -0,0 +1,266 @@
+package qst
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+// MaxResponseDumpBodySize is the maximum number of response body bytes dumped when DumpOptions.MaxBodySize isn't positive.
+var MaxResponseDumpBodySize = 64 << 10
+
+// DumpOptions configures how requests are dumped.
+type DumpOptions struct {
+	// Redaction, if set, redacts secrets from the dump.
+	Redaction *Redaction
+
+	// MaxBodySize, if positive, truncates bodies longer than this many bytes.
+	// Response bodies are only read this far for the dump, or MaxResponseDumpBodySize bytes if MaxBodySize isn't
+	// positive, and the rest is left for the caller to read as it arrives. Event streams aren't read for the dump at all.
+	// Truncated JSON bodies are left out when redacting fields.
+	MaxBodySize int
+
+	// Wire dumps requests as they would be written to the wire by an *http.Transport,
+	// including headers such as "User-Agent" and "Content-Length" which are added when the request is sent.
+	// Otherwise, only what is on the *http.Request is dumped.
+	Wire bool
+}
+
+// WithDumpOptions writes the request to w, as configured by options.
+// If the body can be replayed with GetBody, it is dumped from a copy and left unread.
+func WithDumpOptions(w io.Writer, options DumpOptions) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		dump, err := dumpRequest(request, options, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := w.Write(dump); err != nil {
+			return nil, err
+		}
+
+		return request, nil
+	})
+}
+
+// dumpRequest dumps the request as configured by options.
+// If readBody is false, bodies which can't be replayed with GetBody are left out of the dump rather than read.
+func dumpRequest(request *http.Request, options DumpOptions, readBody bool) ([]byte, error) {
+	var body []byte
+	if readBody || request.GetBody != nil {
+		var err error
+		if body, err = peekBody(request); err != nil {
+			return nil, err
+		}
+	}
+
+	dumped := request.Clone(request.Context())
+	if redaction := options.Redaction; redaction != nil {
+		dumped.Header = redaction.redactHeader(dumped.Header)
+		dumped.URL = redaction.redactURL(dumped.URL)
+		body = redaction.redactBody(dumped.Header.Get("Content-Type"), body)
+
+		// The transport would send the URL password as basic auth.
+		if options.Wire && dumped.URL.User != nil {
+			if _, hasPassword := dumped.URL.User.Password(); hasPassword && dumped.Header.Get("Authorization") == "" {
+				dumped.Header.Set("Authorization", fmt.Sprintf("Basic %s", redacted))
+			}
+
+			dumped.URL.User = nil
+		}
+	}
+
+	body, truncated := truncateBody(body, options.MaxBodySize)
+	includeBody := readBody || request.GetBody != nil
+	if includeBody && request.Body != nil && request.Body != http.NoBody {
+		dumped.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if request.ContentLength > 0 {
+			dumped.ContentLength = int64(len(body))
+		}
+	}
+
+	var (
+		dump []byte
+		err  error
+	)
+
+	if options.Wire {
+		dump, err = httputil.DumpRequestOut(dumped, includeBody)
+	} else {
+		dump, err = httputil.DumpRequest(dumped, includeBody)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return appendTruncation(dump, truncated), nil
+}
+
+// truncateBody truncates body to maxSize bytes if maxSize is positive, and returns the number of bytes removed.
+func truncateBody(body []byte, maxSize int) ([]byte, int) {
+	if maxSize <= 0 || len(body) <= maxSize {
+		return body, 0
+	}
+
+	return body[:maxSize], len(body) - maxSize
+}
+
+func appendTruncation(dump []byte, truncated int) []byte {
+	if truncated == 0 {
+		return dump
+	}
+
+	return append(dump, fmt.Sprintf("\n... (%d bytes truncated)", truncated)...)
+}
+
+// dumpResponse dumps the response as configured by options, replacing its body so that it can still be read.
+// At most the maximum body size is read for the dump; the rest is left to the caller.
+func dumpResponse(response *http.Response, options DumpOptions) ([]byte, error) {
+	if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		dumped := *response
+		dumped.Body = http.NoBody
+		if options.Redaction != nil {
+			dumped.Header = options.Redaction.redactHeader(dumped.Header)
+		}
+
+		dump, err := httputil.DumpResponse(&dumped, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(dump, "... (event stream not dumped)"...), nil
+	}
+
+	maxSize := options.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = MaxResponseDumpBodySize
+	}
+
+	prefix, err := ioutil.ReadAll(io.LimitReader(response.Body, int64(maxSize)+1))
+	if err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), response.Body), response.Body}
+
+	body := prefix
+	isTruncated := len(body) > maxSize
+	if isTruncated {
+		body = body[:maxSize]
+	}
+
+	dumped := *response
+	if redaction := options.Redaction; redaction != nil {
+		dumped.Header = redaction.redactHeader(dumped.Header)
+
+		// A truncated JSON document can't be parsed to redact its fields, so it is left out.
+		if mediaType, _, _ := mime.ParseMediaType(dumped.Header.Get("Content-Type")); isTruncated && isJSONMediaType(mediaType) && len(redaction.JSONFields) > 0 {
+			body = nil
+		} else {
+			body = redaction.redactBody(dumped.Header.Get("Content-Type"), body)
+		}
+	}
+
+	dumped.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if response.ContentLength > 0 {
+		dumped.ContentLength = int64(len(body))
+	}
+
+	dump, err := httputil.DumpResponse(&dumped, true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !isTruncated:
+		return dump, nil
+	case response.ContentLength > 0:
+		return appendTruncation(dump, int(response.ContentLength)-len(body)), nil
+	default:
+		return append(dump, "\n... (truncated)"...), nil
+	}
+}
+
+// RoundTripDumper dumps requests as they are sent, along with their responses and how long they took.
+type RoundTripDumper struct {
+	// Writer is where dumps are written. Each round trip is written with a single call to Write.
+	Writer io.Writer
+
+	// Options configures the dumps. Requests are always dumped as written to the wire, regardless of Options.Wire.
+	// Request bodies which can't be replayed with GetBody are left out, so that streaming bodies stay streaming.
+	Options DumpOptions
+
+	mutex sync.Mutex
+}
+
+type roundTripDumperKey struct{}
+
+// WithRoundTripDump dumps the *http.Request as it is sent, along with its response, to w.
+// It overrides any *RoundTripDumper set on the *Client.
+func WithRoundTripDump(w io.Writer, options DumpOptions) option.Option[*http.Request] {
+	return WithContextValue(roundTripDumperKey{}, &RoundTripDumper{Writer: w, Options: options})
+}
+
+// RoundTripper wraps next so that each round trip is dumped.
+func (d *RoundTripDumper) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		options := d.Options
+		options.Wire = true
+
+		requestDump, err := dumpRequest(request, options, false)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		response, err := next.RoundTrip(request)
+		elapsed := time.Since(start)
+
+		dump := bytes.NewBuffer(requestDump)
+		if err != nil {
+			fmt.Fprintf(dump, "\n\n<-- error after %s: %v\n\n", elapsed, err)
+			return nil, d.write(dump.Bytes(), err)
+		}
+
+		responseDump, dumpErr := dumpResponse(response, d.Options)
+		if dumpErr != nil {
+			return nil, dumpErr
+		}
+
+		fmt.Fprintf(dump, "\n\n<-- %s in %s\n", response.Status, elapsed)
+		dump.Write(responseDump)
+		dump.WriteString("\n\n")
+		if err := d.write(dump.Bytes(), nil); err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+
+		return response, nil
+	})
+}
+
+// write writes dump to the Writer, returning err, or the write error if there is one.
+func (d *RoundTripDumper) write(dump []byte, err error) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, writeErr := d.Writer.Write(dump); writeErr != nil {
+		return writeErr
+	}
+
+	return err
+}
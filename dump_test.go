@@ -0,0 +1,226 @@
+package qst_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDumpOptions(t *testing.T) {
+	t.Run("redaction", func(t *testing.T) {
+		var buffer bytes.Buffer
+		request, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithQuery("access_token", "c0rnfl@k3s"),
+			qst.WithQuery("page", "2"),
+			qst.WithBearerAuth("c0rnfl@k3s"),
+			qst.WithHeader("Cookie", "session=1234; cookie-crisp=chocolate"),
+			qst.WithBodyJSON(map[string]interface{}{
+				"name":  "Life",
+				"owner": map[string]interface{}{"password": "grrreat"},
+				"boxes": []interface{}{map[string]interface{}{"token": "a"}, map[string]interface{}{"token": "b"}},
+			}),
+			qst.WithDumpOptions(&buffer, qst.DumpOptions{Redaction: &qst.Redaction{
+				Headers:    []string{"authorization", "Cookie"},
+				QueryKeys:  []string{"access_token"},
+				JSONFields: []string{"owner.password", "boxes.token"},
+			}}),
+		)
+		require.NoError(t, err)
+
+		expected := "" +
+			"POST /api/cereals?access_token=REDACTED&page=2 HTTP/1.1\r\n" +
+			"Host: breakfast.com\r\n" +
+			"Authorization: Bearer REDACTED\r\n" +
+			"Content-Type: application/json\r\n" +
+			"Cookie: session=REDACTED; cookie-crisp=REDACTED\r\n" +
+			"\r\n" +
+			`{"boxes":[{"token":"REDACTED"},{"token":"REDACTED"}],"name":"Life","owner":{"password":"REDACTED"}}`
+
+		assert.Equal(t, expected, buffer.String())
+		assert.Equal(t, "Bearer c0rnfl@k3s", request.Header.Get("Authorization"))
+		assert.Equal(t, "c0rnfl@k3s", request.URL.Query().Get("access_token"))
+		assert.Contains(t, readAll(t, request.Body), `"password":"grrreat"`)
+	})
+
+	t.Run("form redaction", func(t *testing.T) {
+		var buffer bytes.Buffer
+		_, err := qst.NewPost("https://breakfast.com/api/login",
+			qst.WithBodyForm{"username": {"TonyTheTiger"}, "password": {"grrreat"}},
+			qst.WithDumpOptions(&buffer, qst.DumpOptions{Redaction: &qst.DefaultRedaction}),
+		)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(buffer.String(), "\r\n\r\npassword=REDACTED&username=TonyTheTiger"))
+	})
+
+	t.Run("truncation", func(t *testing.T) {
+		var buffer bytes.Buffer
+		_, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyString("Part of a complete breakfast."),
+			qst.WithDumpOptions(&buffer, qst.DumpOptions{MaxBodySize: 4}),
+		)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(buffer.String(), "\r\n\r\nPart\n... (25 bytes truncated)"))
+	})
+
+	t.Run("wire", func(t *testing.T) {
+		var buffer bytes.Buffer
+		_, err := qst.NewPut("https://breakfast.com/api/cereals",
+			qst.WithUserPassword("TonyTheTiger", "grrreat"),
+			qst.WithBodyString("Part of a complete breakfast."),
+			qst.WithDumpOptions(&buffer, qst.DumpOptions{Redaction: &qst.DefaultRedaction, Wire: true}),
+		)
+		require.NoError(t, err)
+
+		expected := "" +
+			"PUT /api/cereals HTTP/1.1\r\n" +
+			"Host: breakfast.com\r\n" +
+			"User-Agent: Go-http-client/1.1\r\n" +
+			"Content-Length: 29\r\n" +
+			"Authorization: Basic REDACTED\r\n" +
+			"Accept-Encoding: gzip\r\n" +
+			"\r\n" +
+			"Part of a complete breakfast."
+
+		assert.Equal(t, expected, buffer.String())
+	})
+}
+
+func TestRoundTripDumper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1234", Path: "/"})
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"Life","token":"c0rnfl@k3s"}`)
+	}))
+	defer server.Close()
+
+	t.Run("client", func(t *testing.T) {
+		var buffer bytes.Buffer
+		client := qst.NewClient(nil, server.URL)
+		client.Dumper = &qst.RoundTripDumper{Writer: &buffer, Options: qst.DumpOptions{Redaction: &qst.DefaultRedaction}}
+
+		response, err := client.Post("/cereals",
+			qst.WithBearerAuth("c0rnfl@k3s"),
+			qst.WithBodyString("Part of a complete breakfast."),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, `{"name":"Life","token":"c0rnfl@k3s"}`, readAll(t, response.Body))
+
+		dump := buffer.String()
+		assert.True(t, strings.HasPrefix(dump, "POST /cereals HTTP/1.1\r\n"), dump)
+		assert.Contains(t, dump, "User-Agent: Go-http-client/1.1\r\n")
+		assert.Contains(t, dump, "Authorization: Bearer REDACTED\r\n")
+		assert.Contains(t, dump, "\r\n\r\nPart of a complete breakfast.\n\n<-- 200 OK in ")
+		assert.Contains(t, dump, "HTTP/1.1 200 OK\r\n")
+		assert.Contains(t, dump, "Set-Cookie: session=REDACTED; Path=/\r\n")
+		assert.Contains(t, dump, `{"name":"Life","token":"REDACTED"}`)
+		assert.NotContains(t, dump, "c0rnfl@k3s")
+	})
+
+	t.Run("per request, streaming body, truncated response", func(t *testing.T) {
+		var buffer bytes.Buffer
+		response, err := qst.Put(server.URL,
+			qst.WithBodyReader(struct{ io.Reader }{strings.NewReader("Part of a complete breakfast.")}),
+			qst.WithRoundTripDump(&buffer, qst.DumpOptions{MaxBodySize: 4}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, `{"name":"Life","token":"c0rnfl@k3s"}`, readAll(t, response.Body))
+
+		dump := buffer.String()
+		assert.NotContains(t, dump, "Part of a complete breakfast.")
+		assert.Contains(t, dump, "\r\n\r\n{\"na\n... (32 bytes truncated)\n\n")
+	})
+
+	t.Run("response body is read no further than MaxBodySize", func(t *testing.T) {
+		release := make(chan struct{})
+		streaming := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"Life",`)
+			w.(http.Flusher).Flush()
+			<-release
+			fmt.Fprint(w, `"token":"c0rnfl@k3s"}`)
+		}))
+		defer streaming.Close()
+
+		var buffer bytes.Buffer
+		response, err := qst.Get(streaming.URL, qst.WithRoundTripDump(&buffer, qst.DumpOptions{Redaction: &qst.DefaultRedaction, MaxBodySize: 4}))
+		close(release)
+		require.NoError(t, err)
+		assert.Equal(t, `{"name":"Life","token":"c0rnfl@k3s"}`, readAll(t, response.Body))
+
+		dump := buffer.String()
+		assert.NotContains(t, dump, `{"na`)
+		assert.True(t, strings.HasSuffix(dump, "\r\n\r\n\n... (truncated)\n\n"), dump)
+	})
+
+	t.Run("response body is read no further than MaxResponseDumpBodySize by default", func(t *testing.T) {
+		defer func(size int) { qst.MaxResponseDumpBodySize = size }(qst.MaxResponseDumpBodySize)
+		qst.MaxResponseDumpBodySize = 4
+
+		release := make(chan struct{})
+		streaming := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "Part of ")
+			w.(http.Flusher).Flush()
+			<-release
+			fmt.Fprint(w, "a complete breakfast.")
+		}))
+		defer streaming.Close()
+
+		var buffer bytes.Buffer
+		response, err := qst.Get(streaming.URL, qst.WithRoundTripDump(&buffer, qst.DumpOptions{}))
+		close(release)
+		require.NoError(t, err)
+		assert.Equal(t, "Part of a complete breakfast.", readAll(t, response.Body))
+		assert.Contains(t, buffer.String(), "\r\n\r\n4\r\nPart\r\n")
+		assert.True(t, strings.HasSuffix(buffer.String(), "\n... (truncated)\n\n"), buffer.String())
+	})
+
+	t.Run("event streams aren't read", func(t *testing.T) {
+		release := make(chan struct{})
+		events := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-release
+			fmt.Fprint(w, "data: Life\n\n")
+		}))
+		defer events.Close()
+
+		var buffer bytes.Buffer
+		response, err := qst.Get(events.URL, qst.WithRoundTripDump(&buffer, qst.DumpOptions{}))
+		close(release)
+		require.NoError(t, err)
+		assert.Equal(t, "data: Life\n\n", readAll(t, response.Body))
+		assert.Contains(t, buffer.String(), "... (event stream not dumped)")
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		response, err := qst.Get(server.URL, qst.WithRoundTripDump(failingWriter{}, qst.DumpOptions{}))
+		assert.EqualError(t, err, fmt.Sprintf("Get %q: write failed", server.URL))
+		assert.Nil(t, response)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+
+		var buffer bytes.Buffer
+		_, err := qst.Get(closed.URL, qst.WithRoundTripDump(&buffer, qst.DumpOptions{}))
+		assert.Error(t, err)
+		assert.Contains(t, buffer.String(), "<-- error after ")
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
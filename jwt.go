@@ -0,0 +1,264 @@
+package qst
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+const defaultJWTTTL = 5 * time.Minute
+
+// JWTSigner signs JSON Web Tokens.
+type JWTSigner interface {
+	// Algorithm is the "alg" header of tokens, e.g. "HS256".
+	Algorithm() string
+
+	// Sign returns the signature of the token's signing input.
+	Sign(signingInput []byte) ([]byte, error)
+}
+
+// HS256 returns a JWTSigner which signs with HMAC using SHA-256 and key.
+func HS256(key []byte) JWTSigner {
+	return hs256Signer(key)
+}
+
+type hs256Signer []byte
+
+func (s hs256Signer) Algorithm() string { return "HS256" }
+
+func (s hs256Signer) Sign(signingInput []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s)
+	mac.Write(signingInput)
+	return mac.Sum(nil), nil
+}
+
+// RS256 returns a JWTSigner which signs with RSASSA-PKCS1-v1_5 using SHA-256 and key.
+func RS256(key *rsa.PrivateKey) JWTSigner {
+	return rs256Signer{key: key}
+}
+
+type rs256Signer struct {
+	key *rsa.PrivateKey
+}
+
+func (s rs256Signer) Algorithm() string { return "RS256" }
+
+func (s rs256Signer) Sign(signingInput []byte) ([]byte, error) {
+	digest := sha256.Sum256(signingInput)
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+}
+
+// ES256 returns a JWTSigner which signs with ECDSA using P-256, SHA-256 and key.
+// Signing fails if key isn't a P-256 key.
+func ES256(key *ecdsa.PrivateKey) JWTSigner {
+	return es256Signer{key: key}
+}
+
+type es256Signer struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s es256Signer) Algorithm() string { return "ES256" }
+
+// Sign returns the signature as the concatenated r and s values, as JWS requires, rather than ASN.1.
+func (s es256Signer) Sign(signingInput []byte) ([]byte, error) {
+	if s.key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("ES256 requires a P-256 key, not %s", s.key.Curve.Params().Name)
+	}
+
+	digest := sha256.Sum256(signingInput)
+	r, sValue, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sValue.FillBytes(signature[32:])
+	return signature, nil
+}
+
+// EdDSA returns a JWTSigner which signs with EdDSA using Ed25519 and key.
+func EdDSA(key ed25519.PrivateKey) JWTSigner {
+	return edDSASigner(key)
+}
+
+type edDSASigner ed25519.PrivateKey
+
+func (s edDSASigner) Algorithm() string { return "EdDSA" }
+
+func (s edDSASigner) Sign(signingInput []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), signingInput), nil
+}
+
+// JWTClaims configures the tokens minted by JWTAuth.
+type JWTClaims struct {
+	// Issuer, Subject and Audience, if set, are the "iss", "sub" and "aud" claims.
+	Issuer   string
+	Subject  string
+	Audience string
+
+	// Claims are additional claims. They don't override the claims set by JWTAuth.
+	Claims map[string]interface{}
+
+	// TTL is the lifetime of tokens. If zero, 5 minutes is used.
+	TTL time.Duration
+
+	// BindRequest adds "htm" and "htu" claims with the method and URL (without query or fragment) of the request.
+	BindRequest bool
+
+	// KeyID, if set, is the "kid" header of tokens.
+	KeyID string
+}
+
+// WithJWTAuth authorizes the *http.Request with a bearer JWT signed by signer when it is sent. See JWTAuth.
+// Tokens are cached by the returned option, so it must be shared between requests, e.g. in Client.Defaults, for them
+// to be reused.
+func WithJWTAuth(signer JWTSigner, claims JWTClaims) option.Option[*http.Request] {
+	return WithMiddleware(JWTAuth(signer, claims))
+}
+
+// JWTAuth returns a Middleware which authorizes requests with bearer JWTs signed by signer.
+// Tokens are stamped with "iat", "exp" and "jti" claims, and reused until shortly before they expire. With
+// BindRequest, tokens are reused only for the same method and URL.
+func JWTAuth(signer JWTSigner, claims JWTClaims) Middleware {
+	minter := &jwtMinter{signer: signer, claims: claims, tokens: make(map[string]cachedJWT)}
+	return minter.RoundTripper
+}
+
+type jwtMinter struct {
+	signer JWTSigner
+	claims JWTClaims
+
+	mutex  sync.Mutex
+	tokens map[string]cachedJWT
+}
+
+type cachedJWT struct {
+	token  string
+	expiry time.Time
+}
+
+func (m *jwtMinter) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		token, err := m.token(request)
+		if err != nil {
+			return nil, err
+		}
+
+		authorized := request.Clone(request.Context())
+		authorized.Header.Set("Authorization", "Bearer "+token)
+		return next.RoundTrip(authorized)
+	})
+}
+
+// token returns a cached token for request if one is valid for long enough, and otherwise mints a new one.
+func (m *jwtMinter) token(request *http.Request) (string, error) {
+	ttl := m.claims.TTL
+	if ttl <= 0 {
+		ttl = defaultJWTTTL
+	}
+
+	leeway := defaultExpiryLeeway
+	if leeway > ttl/2 {
+		leeway = ttl / 2
+	}
+
+	var method, target string
+	if m.claims.BindRequest {
+		url := *request.URL
+		url.RawQuery, url.Fragment, url.RawFragment = "", "", ""
+		method, target = request.Method, url.String()
+	}
+
+	key := method + " " + target
+	now := time.Now()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if cached, ok := m.tokens[key]; ok && now.Add(leeway).Before(cached.expiry) {
+		return cached.token, nil
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	payload := make(map[string]interface{}, len(m.claims.Claims)+8)
+	for name, value := range m.claims.Claims {
+		payload[name] = value
+	}
+
+	for name, value := range map[string]string{"iss": m.claims.Issuer, "sub": m.claims.Subject, "aud": m.claims.Audience, "htm": method, "htu": target} {
+		if value != "" {
+			payload[name] = value
+		}
+	}
+
+	expiry := now.Add(ttl)
+	payload["iat"] = now.Unix()
+	payload["exp"] = expiry.Unix()
+	payload["jti"] = hex.EncodeToString(jti)
+
+	header := map[string]string{"alg": m.signer.Algorithm(), "typ": "JWT"}
+	if m.claims.KeyID != "" {
+		header["kid"] = m.claims.KeyID
+	}
+
+	token, err := signJWT(m.signer, header, payload)
+	if err != nil {
+		return "", err
+	}
+
+	for key, cached := range m.tokens {
+		if !now.Before(cached.expiry) {
+			delete(m.tokens, key)
+		}
+	}
+
+	m.tokens[key] = cachedJWT{token: token, expiry: expiry}
+	return token, nil
+}
+
+// signJWT returns the compact serialization of a JWT with header and payload, signed by signer.
+func signJWT(signer JWTSigner, header, payload interface{}) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(headerJSON),
+		base64.RawURLEncoding.EncodeToString(payloadJSON),
+	}, ".")
+
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
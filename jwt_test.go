@@ -0,0 +1,189 @@
+package qst_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseJWT returns the decoded header, payload, signing input and signature of token.
+func parseJWT(t *testing.T, token string) (map[string]interface{}, map[string]interface{}, []byte, []byte) {
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	decode := func(part string, v interface{}) []byte {
+		data, err := base64.RawURLEncoding.DecodeString(part)
+		require.NoError(t, err)
+		if v != nil {
+			require.NoError(t, json.Unmarshal(data, v))
+		}
+
+		return data
+	}
+
+	var header, payload map[string]interface{}
+	decode(parts[0], &header)
+	decode(parts[1], &payload)
+	return header, payload, []byte(parts[0] + "." + parts[1]), decode(parts[2], nil)
+}
+
+func TestWithJWTAuth(t *testing.T) {
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	lastToken := func(t *testing.T) string {
+		require.NotEmpty(t, authorizations)
+		last := authorizations[len(authorizations)-1]
+		require.True(t, strings.HasPrefix(last, "Bearer "))
+		return strings.TrimPrefix(last, "Bearer ")
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		signer qst.JWTSigner
+		verify func(t *testing.T, signingInput, signature []byte)
+	}{
+		"HS256": {
+			signer: qst.HS256([]byte("s3cr3t")),
+			verify: func(t *testing.T, signingInput, signature []byte) {
+				mac := hmac.New(sha256.New, []byte("s3cr3t"))
+				mac.Write(signingInput)
+				assert.Equal(t, mac.Sum(nil), signature)
+			},
+		},
+		"RS256": {
+			signer: qst.RS256(rsaKey),
+			verify: func(t *testing.T, signingInput, signature []byte) {
+				digest := sha256.Sum256(signingInput)
+				assert.NoError(t, rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature))
+			},
+		},
+		"ES256": {
+			signer: qst.ES256(ecdsaKey),
+			verify: func(t *testing.T, signingInput, signature []byte) {
+				require.Len(t, signature, 64)
+				digest := sha256.Sum256(signingInput)
+				r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+				assert.True(t, ecdsa.Verify(&ecdsaKey.PublicKey, digest[:], r, s))
+			},
+		},
+		"EdDSA": {
+			signer: qst.EdDSA(edPrivateKey),
+			verify: func(t *testing.T, signingInput, signature []byte) {
+				assert.True(t, ed25519.Verify(edPublicKey, signingInput, signature))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := qst.Get(server.URL, qst.WithJWTAuth(testCase.signer, qst.JWTClaims{
+				Issuer:   "cereal-app",
+				Subject:  "tony",
+				Audience: "breakfast.com",
+				Claims:   map[string]interface{}{"scope": "cereals:read", "iat": 0},
+				KeyID:    "key-1",
+			}))
+			require.NoError(t, err)
+
+			header, payload, signingInput, signature := parseJWT(t, lastToken(t))
+			assert.Equal(t, map[string]interface{}{"alg": name, "typ": "JWT", "kid": "key-1"}, header)
+			assert.Equal(t, "cereal-app", payload["iss"])
+			assert.Equal(t, "tony", payload["sub"])
+			assert.Equal(t, "breakfast.com", payload["aud"])
+			assert.Equal(t, "cereals:read", payload["scope"])
+			assert.InDelta(t, time.Now().Unix(), payload["iat"], 5)
+			assert.InDelta(t, time.Now().Add(5*time.Minute).Unix(), payload["exp"], 5)
+			assert.Len(t, payload["jti"], 32)
+			testCase.verify(t, signingInput, signature)
+		})
+	}
+
+	t.Run("caches until near expiry", func(t *testing.T) {
+		client := qst.NewClient(http.DefaultClient, server.URL, qst.WithJWTAuth(qst.HS256([]byte("s3cr3t")), qst.JWTClaims{TTL: time.Second}))
+
+		_, err := client.Get("/cereals")
+		require.NoError(t, err)
+		first := lastToken(t)
+
+		_, err = client.Get("/cereals")
+		require.NoError(t, err)
+		assert.Equal(t, first, lastToken(t))
+
+		time.Sleep(600 * time.Millisecond)
+		_, err = client.Get("/cereals")
+		require.NoError(t, err)
+		assert.NotEqual(t, first, lastToken(t))
+	})
+
+	t.Run("JWTAuth middleware caches across requests", func(t *testing.T) {
+		client := qst.NewClient(http.DefaultClient, server.URL)
+		client.Middleware = append(client.Middleware, qst.JWTAuth(qst.HS256([]byte("s3cr3t")), qst.JWTClaims{}))
+
+		_, err := client.Get("/cereals")
+		require.NoError(t, err)
+		first := lastToken(t)
+
+		_, err = client.Get("/cereals")
+		require.NoError(t, err)
+		assert.Equal(t, first, lastToken(t))
+	})
+
+	t.Run("binds to the request", func(t *testing.T) {
+		client := qst.NewClient(http.DefaultClient, server.URL, qst.WithJWTAuth(qst.HS256([]byte("s3cr3t")), qst.JWTClaims{BindRequest: true}))
+
+		_, err := client.Get("/cereals", qst.WithQuery("page", "1"))
+		require.NoError(t, err)
+		first := lastToken(t)
+
+		_, payload, _, _ := parseJWT(t, first)
+		assert.Equal(t, http.MethodGet, payload["htm"])
+		assert.Equal(t, server.URL+"/cereals", payload["htu"])
+
+		_, err = client.Get("/cereals", qst.WithQuery("page", "2"))
+		require.NoError(t, err)
+		assert.Equal(t, first, lastToken(t))
+
+		_, err = client.Delete("/cereals")
+		require.NoError(t, err)
+		_, payload, _, _ = parseJWT(t, lastToken(t))
+		assert.Equal(t, http.MethodDelete, payload["htm"])
+	})
+
+	t.Run("ES256 with a key on another curve", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		require.NoError(t, err)
+
+		_, err = qst.Get(server.URL, qst.WithJWTAuth(qst.ES256(key), qst.JWTClaims{}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ES256 requires a P-256 key, not P-384")
+	})
+}
@@ -18,6 +18,16 @@ func Get(url string, options ...option.Option[*http.Request]) (*http.Response, e
 	return Do(http.MethodGet, url, options...)
 }
 
+// GetJSON makes a GET request and decodes the JSON response body into a T.
+func GetJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodGet, url, options...)
+}
+
+// GetXML makes a GET request and decodes the XML response body into a T.
+func GetXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodGet, url, options...)
+}
+
 // NewHead builds a new *http.Request with method HEAD.
 func NewHead(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
 	return New(http.MethodHead, url, options...)
@@ -38,6 +48,16 @@ func Post(url string, options ...option.Option[*http.Request]) (*http.Response,
 	return Do(http.MethodPost, url, options...)
 }
 
+// PostJSON makes a POST request and decodes the JSON response body into a T.
+func PostJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodPost, url, options...)
+}
+
+// PostXML makes a POST request and decodes the XML response body into a T.
+func PostXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodPost, url, options...)
+}
+
 // NewPut builds a new *http.Request with method PUT.
 func NewPut(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
 	return New(http.MethodPut, url, options...)
@@ -48,6 +68,16 @@ func Put(url string, options ...option.Option[*http.Request]) (*http.Response, e
 	return Do(http.MethodPut, url, options...)
 }
 
+// PutJSON makes a PUT request and decodes the JSON response body into a T.
+func PutJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodPut, url, options...)
+}
+
+// PutXML makes a PUT request and decodes the XML response body into a T.
+func PutXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodPut, url, options...)
+}
+
 // NewPatch builds a new *http.Request with method PATCH.
 func NewPatch(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
 	return New(http.MethodPatch, url, options...)
@@ -58,6 +88,16 @@ func Patch(url string, options ...option.Option[*http.Request]) (*http.Response,
 	return Do(http.MethodPatch, url, options...)
 }
 
+// PatchJSON makes a PATCH request and decodes the JSON response body into a T.
+func PatchJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodPatch, url, options...)
+}
+
+// PatchXML makes a PATCH request and decodes the XML response body into a T.
+func PatchXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodPatch, url, options...)
+}
+
 // NewDelete builds a new *http.Request with method DELETE.
 func NewDelete(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
 	return New(http.MethodDelete, url, options...)
@@ -68,6 +108,16 @@ func Delete(url string, options ...option.Option[*http.Request]) (*http.Response
 	return Do(http.MethodDelete, url, options...)
 }
 
+// DeleteJSON makes a DELETE request and decodes the JSON response body into a T.
+func DeleteJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodDelete, url, options...)
+}
+
+// DeleteXML makes a DELETE request and decodes the XML response body into a T.
+func DeleteXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodDelete, url, options...)
+}
+
 // NewConnect builds a new *http.Request with method CONNECT.
 func NewConnect(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
 	return New(http.MethodConnect, url, options...)
@@ -78,6 +128,16 @@ func Connect(url string, options ...option.Option[*http.Request]) (*http.Respons
 	return Do(http.MethodConnect, url, options...)
 }
 
+// ConnectJSON makes a CONNECT request and decodes the JSON response body into a T.
+func ConnectJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodConnect, url, options...)
+}
+
+// ConnectXML makes a CONNECT request and decodes the XML response body into a T.
+func ConnectXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodConnect, url, options...)
+}
+
 // NewOptions builds a new *http.Request with method OPTIONS.
 func NewOptions(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
 	return New(http.MethodOptions, url, options...)
@@ -88,6 +148,16 @@ func Options(url string, options ...option.Option[*http.Request]) (*http.Respons
 	return Do(http.MethodOptions, url, options...)
 }
 
+// OptionsJSON makes a OPTIONS request and decodes the JSON response body into a T.
+func OptionsJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodOptions, url, options...)
+}
+
+// OptionsXML makes a OPTIONS request and decodes the XML response body into a T.
+func OptionsXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodOptions, url, options...)
+}
+
 // NewTrace builds a new *http.Request with method TRACE.
 func NewTrace(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
 	return New(http.MethodTrace, url, options...)
@@ -97,3 +167,103 @@ func NewTrace(url string, options ...option.Option[*http.Request]) (*http.Reques
 func Trace(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
 	return Do(http.MethodTrace, url, options...)
 }
+
+// TraceJSON makes a TRACE request and decodes the JSON response body into a T.
+func TraceJSON[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoJSON[T](http.MethodTrace, url, options...)
+}
+
+// TraceXML makes a TRACE request and decodes the XML response body into a T.
+func TraceXML[T any](url string, options ...option.Option[*http.Request]) (T, *http.Response, error) {
+	return DoXML[T](http.MethodTrace, url, options...)
+}
+
+// NewGet builds a new *http.Request with method GET using the *Client.
+func (c *Client) NewGet(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodGet, url, options...)
+}
+
+// Get makes a GET request using the *Client and returns the *http.Response.
+func (c *Client) Get(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodGet, url, options...)
+}
+
+// NewHead builds a new *http.Request with method HEAD using the *Client.
+func (c *Client) NewHead(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodHead, url, options...)
+}
+
+// Head makes a HEAD request using the *Client and returns the *http.Response.
+func (c *Client) Head(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodHead, url, options...)
+}
+
+// NewPost builds a new *http.Request with method POST using the *Client.
+func (c *Client) NewPost(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodPost, url, options...)
+}
+
+// Post makes a POST request using the *Client and returns the *http.Response.
+func (c *Client) Post(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodPost, url, options...)
+}
+
+// NewPut builds a new *http.Request with method PUT using the *Client.
+func (c *Client) NewPut(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodPut, url, options...)
+}
+
+// Put makes a PUT request using the *Client and returns the *http.Response.
+func (c *Client) Put(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodPut, url, options...)
+}
+
+// NewPatch builds a new *http.Request with method PATCH using the *Client.
+func (c *Client) NewPatch(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodPatch, url, options...)
+}
+
+// Patch makes a PATCH request using the *Client and returns the *http.Response.
+func (c *Client) Patch(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodPatch, url, options...)
+}
+
+// NewDelete builds a new *http.Request with method DELETE using the *Client.
+func (c *Client) NewDelete(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodDelete, url, options...)
+}
+
+// Delete makes a DELETE request using the *Client and returns the *http.Response.
+func (c *Client) Delete(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodDelete, url, options...)
+}
+
+// NewConnect builds a new *http.Request with method CONNECT using the *Client.
+func (c *Client) NewConnect(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodConnect, url, options...)
+}
+
+// Connect makes a CONNECT request using the *Client and returns the *http.Response.
+func (c *Client) Connect(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodConnect, url, options...)
+}
+
+// NewOptions builds a new *http.Request with method OPTIONS using the *Client.
+func (c *Client) NewOptions(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodOptions, url, options...)
+}
+
+// Options makes a OPTIONS request using the *Client and returns the *http.Response.
+func (c *Client) Options(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodOptions, url, options...)
+}
+
+// NewTrace builds a new *http.Request with method TRACE using the *Client.
+func (c *Client) NewTrace(url string, options ...option.Option[*http.Request]) (*http.Request, error) {
+	return c.New(http.MethodTrace, url, options...)
+}
+
+// Trace makes a TRACE request using the *Client and returns the *http.Response.
+func (c *Client) Trace(url string, options ...option.Option[*http.Request]) (*http.Response, error) {
+	return c.Do(http.MethodTrace, url, options...)
+}
@@ -0,0 +1,46 @@
+package qst
+
+import (
+	"net/http"
+
+	"github.com/broothie/option"
+)
+
+// Middleware wraps an http.RoundTripper, e.g. to add logging, metrics, or authentication to requests.
+//
+// When a *Client sends a request, middleware are applied from outermost to innermost in this order:
+// the *Client Middleware, the middleware applied with WithMiddleware, the *RetryPolicy, signing (the "Content-Digest"
+// header from WithContentDigest, then the signatures from WithMessageSignature or Client.Signer), and then the
+// *RoundTripDumper.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+type middlewareKey struct{}
+
+// WithMiddleware applies middleware to the *http.Request, which wrap the transport when it is sent by a *Client.
+// Middleware from multiple calls accumulate, with earlier middleware wrapping later ones.
+func WithMiddleware(middleware ...Middleware) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		existing := requestMiddleware(request)
+		combined := make([]Middleware, 0, len(existing)+len(middleware))
+		combined = append(combined, existing...)
+		combined = append(combined, middleware...)
+
+		return WithContextValue(middlewareKey{}, combined).Apply(request)
+	})
+}
+
+// Chain combines middleware into a single Middleware, with earlier middleware wrapping later ones.
+func Chain(middleware ...Middleware) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+
+		return next
+	}
+}
+
+func requestMiddleware(request *http.Request) []Middleware {
+	middleware, _ := request.Context().Value(middlewareKey{}).([]Middleware)
+	return middleware
+}
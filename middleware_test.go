@@ -0,0 +1,80 @@
+package qst_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["X-Trace"] = r.Header.Values("X-Trace")
+	}))
+	defer server.Close()
+
+	trace := func(name string, calls *[]string) qst.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+				*calls = append(*calls, name)
+				request = request.Clone(request.Context())
+				request.Header.Add("X-Trace", name)
+				return next.RoundTrip(request)
+			})
+		}
+	}
+
+	t.Run("order", func(t *testing.T) {
+		var calls []string
+		client := qst.NewClient(nil, server.URL)
+		client.Middleware = []qst.Middleware{trace("client-1", &calls), trace("client-2", &calls)}
+
+		response, err := client.Get("/",
+			qst.WithMiddleware(trace("request-1", &calls)),
+			qst.WithMiddleware(trace("request-2", &calls), trace("request-3", &calls)),
+		)
+
+		require.NoError(t, err)
+		expected := []string{"client-1", "client-2", "request-1", "request-2", "request-3"}
+		assert.Equal(t, expected, calls)
+		assert.Equal(t, expected, response.Header.Values("X-Trace"))
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		var calls []string
+		_, err := qst.Get(server.URL, qst.WithMiddleware(qst.Chain(trace("a", &calls), trace("b", &calls))))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, calls)
+	})
+
+	t.Run("retries run inside", func(t *testing.T) {
+		attempts := 0
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if attempts++; attempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer flaky.Close()
+
+		var calls []string
+		client := qst.NewClient(nil, flaky.URL, qst.WithMiddleware(trace("request", &calls)))
+		client.Retry = &qst.RetryPolicy{BaseDelay: time.Millisecond}
+		client.Middleware = []qst.Middleware{trace("client", &calls)}
+
+		response, err := client.Get("/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, []string{"client", "request"}, calls)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
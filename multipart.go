@@ -0,0 +1,223 @@
+package qst
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/broothie/option"
+)
+
+// MultipartPart is a part of a multipart/form-data body.
+type MultipartPart struct {
+	name       string
+	fileName   string
+	header     textproto.MIMEHeader
+	open       func() (io.ReadCloser, error)
+	replayable bool
+}
+
+// MultipartField returns a form field MultipartPart.
+func MultipartField(name, value string) MultipartPart {
+	return MultipartPart{
+		name:       name,
+		open:       func() (io.ReadCloser, error) { return ioutil.NopCloser(strings.NewReader(value)), nil },
+		replayable: true,
+	}
+}
+
+// MultipartFile returns a file MultipartPart whose content is read from r.
+func MultipartFile(name, fileName string, r io.Reader) MultipartPart {
+	return MultipartPart{
+		name:     name,
+		fileName: fileName,
+		open:     func() (io.ReadCloser, error) { return ioutil.NopCloser(r), nil },
+	}
+}
+
+// MultipartFilePath returns a file MultipartPart whose content is read from the file at path when the body is written.
+func MultipartFilePath(name, path string) MultipartPart {
+	return MultipartPart{
+		name:       name,
+		fileName:   filepath.Base(path),
+		open:       func() (io.ReadCloser, error) { return os.Open(path) }, // #nosec G304 -- the path is provided by the caller
+		replayable: true,
+	}
+}
+
+// WithContentType returns a copy of the MultipartPart with a "Content-Type" header.
+// File parts default to "application/octet-stream".
+func (p MultipartPart) WithContentType(contentType string) MultipartPart {
+	return p.WithHeader("Content-Type", contentType)
+}
+
+// WithHeader returns a copy of the MultipartPart with a key/value pair added to its headers.
+func (p MultipartPart) WithHeader(key, value string) MultipartPart {
+	header := make(textproto.MIMEHeader, len(p.header)+1)
+	for k, v := range p.header {
+		header[k] = append([]string(nil), v...)
+	}
+
+	header.Add(key, value)
+	p.header = header
+	return p
+}
+
+func (p MultipartPart) mimeHeader() textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader, len(p.header)+2)
+	for key, values := range p.header {
+		header[key] = values
+	}
+
+	disposition := fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(p.name))
+	if p.fileName != "" {
+		disposition = fmt.Sprintf(`%s; filename="%s"`, disposition, escapeQuotes(p.fileName))
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/octet-stream")
+		}
+	}
+
+	header.Set("Content-Disposition", disposition)
+	return header
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// WithBodyMultipart encodes the parts as multipart/form-data and applies the result to the *http.Request body.
+// The body is buffered in memory, so ContentLength and GetBody are set.
+func WithBodyMultipart(parts ...MultipartPart) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		body := new(bytes.Buffer)
+		boundary := newMultipartBoundary()
+		if err := writeMultipart(body, boundary, parts); err != nil {
+			return nil, err
+		}
+
+		return option.Apply(request,
+			WithContentTypeHeader(multipartContentType(boundary)),
+			WithBodyBytes(body.Bytes()),
+		)
+	})
+}
+
+// WithBodyMultipartStream encodes the parts as multipart/form-data and streams the result to the *http.Request body,
+// so that large files aren't buffered in memory.
+// GetBody is set only if every part can be read again, i.e. it was built with MultipartField or MultipartFilePath.
+func WithBodyMultipartStream(parts ...MultipartPart) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		boundary := newMultipartBoundary()
+		getBody := func() (io.ReadCloser, error) {
+			return newPipeBody(func(w io.Writer) error { return writeMultipart(w, boundary, parts) }), nil
+		}
+
+		body, _ := getBody()
+		request, err := option.Apply(request,
+			WithContentTypeHeader(multipartContentType(boundary)),
+			WithBody(body),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		replayable := true
+		for _, part := range parts {
+			replayable = replayable && part.replayable
+		}
+
+		if replayable {
+			request.GetBody = getBody
+		}
+
+		return request, nil
+	})
+}
+
+func writeMultipart(w io.Writer, boundary string, parts []MultipartPart) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		partWriter, err := writer.CreatePart(part.mimeHeader())
+		if err != nil {
+			return err
+		}
+
+		body, err := part.open()
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(partWriter, body)
+		if closeErr := body.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func newMultipartBoundary() string {
+	return multipart.NewWriter(ioutil.Discard).Boundary()
+}
+
+func multipartContentType(boundary string) string {
+	return fmt.Sprintf("multipart/form-data; boundary=%s", boundary)
+}
+
+// pipeBody is an io.ReadCloser whose content is produced by write in a goroutine, started on the first Read.
+type pipeBody struct {
+	once   sync.Once
+	write  func(io.Writer) error
+	reader *io.PipeReader
+	writer *io.PipeWriter
+
+	// source, if set, is closed by Close if the writer was never started, since otherwise write closes it.
+	source io.Closer
+}
+
+func newPipeBody(write func(io.Writer) error) *pipeBody {
+	reader, writer := io.Pipe()
+	return &pipeBody{write: write, reader: reader, writer: writer}
+}
+
+// Read reads from the pipe, starting the writer if it hasn't been started yet.
+func (b *pipeBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		go func() { b.writer.CloseWithError(b.write(b.writer)) }()
+	})
+
+	return b.reader.Read(p)
+}
+
+// Close closes the pipe, causing any in-progress write to fail. If the writer was never started, it never will be.
+func (b *pipeBody) Close() error {
+	started := true
+	b.once.Do(func() { started = false })
+
+	err := b.reader.Close()
+	if !started && b.source != nil {
+		if closeErr := b.source.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,134 @@
+package qst_test
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/broothie/option"
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithBodyMultipart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingredients.txt")
+	require.NoError(t, os.WriteFile(path, []byte("corn, sugar, salt"), 0o600))
+
+	testCases := map[string]func(parts ...qst.MultipartPart) option.Option[*http.Request]{
+		"buffered":  qst.WithBodyMultipart,
+		"streaming": qst.WithBodyMultipartStream,
+	}
+
+	for name, withBody := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				require.NoError(t, err)
+				assert.Equal(t, "multipart/form-data", mediaType)
+
+				reader := multipart.NewReader(r.Body, params["boundary"])
+
+				part, err := reader.NextPart()
+				require.NoError(t, err)
+				assert.Equal(t, "name", part.FormName())
+				assert.Equal(t, "", part.FileName())
+				assert.Equal(t, "Corn Flakes", readAll(t, part))
+
+				part, err = reader.NextPart()
+				require.NoError(t, err)
+				assert.Equal(t, "box", part.FormName())
+				assert.Equal(t, `box "front".png`, part.FileName())
+				assert.Equal(t, "image/png", part.Header.Get("Content-Type"))
+				assert.Equal(t, "front", part.Header.Get("X-Side"))
+				assert.Equal(t, "not really a png", readAll(t, part))
+
+				part, err = reader.NextPart()
+				require.NoError(t, err)
+				assert.Equal(t, "ingredients", part.FormName())
+				assert.Equal(t, "ingredients.txt", part.FileName())
+				assert.Equal(t, "application/octet-stream", part.Header.Get("Content-Type"))
+				assert.Equal(t, "corn, sugar, salt", readAll(t, part))
+
+				_, err = reader.NextPart()
+				assert.Error(t, err)
+			}))
+			defer server.Close()
+
+			response, err := qst.Post(server.URL, withBody(
+				qst.MultipartField("name", "Corn Flakes"),
+				qst.MultipartFile("box", `box "front".png`, strings.NewReader("not really a png")).
+					WithContentType("image/png").
+					WithHeader("X-Side", "front"),
+				qst.MultipartFilePath("ingredients", path),
+			))
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+		})
+	}
+
+	t.Run("buffered sets ContentLength and GetBody", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyMultipart(qst.MultipartFile("box", "box.png", strings.NewReader("not really a png"))),
+		)
+
+		require.NoError(t, err)
+		assert.Positive(t, request.ContentLength)
+		assert.NotNil(t, request.GetBody)
+	})
+
+	t.Run("streaming sets GetBody only if parts are replayable", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyMultipartStream(qst.MultipartField("name", "Corn Flakes"), qst.MultipartFilePath("ingredients", path)),
+		)
+
+		require.NoError(t, err)
+		require.NotNil(t, request.GetBody)
+
+		first := readAll(t, request.Body)
+		replayed, err := request.GetBody()
+		require.NoError(t, err)
+		assert.Equal(t, first, readAll(t, replayed))
+
+		request, err = qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyMultipartStream(qst.MultipartFile("box", "box.png", strings.NewReader("not really a png"))),
+		)
+
+		require.NoError(t, err)
+		assert.Nil(t, request.GetBody)
+	})
+
+	t.Run("buffered file error", func(t *testing.T) {
+		_, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyMultipart(qst.MultipartFilePath("ingredients", filepath.Join(t.TempDir(), "missing.txt"))),
+		)
+
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("streaming file error", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyMultipartStream(qst.MultipartFilePath("ingredients", filepath.Join(t.TempDir(), "missing.txt"))),
+		)
+		require.NoError(t, err)
+
+		_, err = ioutil.ReadAll(request.Body)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	t.Helper()
+
+	body, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return string(body)
+}
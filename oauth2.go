@@ -0,0 +1,369 @@
+package qst
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	pkgurl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+const (
+	defaultExpiryLeeway = 10 * time.Second
+	tokenRefreshTimeout = 30 * time.Second
+)
+
+// Token is an OAuth 2.0 access token.
+type Token struct {
+	// AccessToken is the token which authorizes requests.
+	AccessToken string
+
+	// TokenType is the type of the token, e.g. "Bearer".
+	TokenType string
+
+	// RefreshToken, if set, can be used to obtain a new token.
+	RefreshToken string
+
+	// Expiry is when the token expires. A zero Expiry means the token doesn't expire.
+	Expiry time.Time
+}
+
+// valid reports whether the token is set and won't expire within leeway.
+func (t *Token) valid(leeway time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+
+	return t.Expiry.IsZero() || time.Now().Add(leeway).Before(t.Expiry)
+}
+
+// authorization returns the "Authorization" header value for the token.
+func (t *Token) authorization() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	return fmt.Sprintf("%s %s", tokenType, t.AccessToken)
+}
+
+// TokenSource provides OAuth 2.0 tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// ClientCredentialsGrant is a TokenSource which obtains tokens with the client credentials grant (RFC 6749 section 4.4).
+// It requests a new token on every call, so it is usually wrapped with NewCachedTokenSource.
+type ClientCredentialsGrant struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string
+
+	// ClientID and ClientSecret are the client's credentials.
+	ClientID     string
+	ClientSecret string
+
+	// Scopes are the scopes requested.
+	Scopes []string
+
+	// EndpointParams are additional parameters sent to the token endpoint.
+	EndpointParams pkgurl.Values
+
+	// AuthInBody sends the client credentials in the request body, rather than with basic auth.
+	AuthInBody bool
+
+	// HTTPClient is the *http.Client used to make token requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// Token requests a new token from the token endpoint.
+func (g *ClientCredentialsGrant) Token(ctx context.Context) (*Token, error) {
+	params := pkgurl.Values{"grant_type": {"client_credentials"}}
+	if len(g.Scopes) > 0 {
+		params.Set("scope", strings.Join(g.Scopes, " "))
+	}
+
+	for key, values := range g.EndpointParams {
+		params[key] = values
+	}
+
+	return requestToken(ctx, g.HTTPClient, g.TokenURL, g.ClientID, g.ClientSecret, g.AuthInBody, params)
+}
+
+// RefreshTokenGrant is a TokenSource which obtains tokens with the refresh token grant (RFC 6749 section 6).
+// If the authorization server issues a new refresh token, it replaces RefreshToken for subsequent calls.
+// It requests a new token on every call, so it is usually wrapped with NewCachedTokenSource.
+type RefreshTokenGrant struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string
+
+	// ClientID and ClientSecret are the client's credentials.
+	ClientID     string
+	ClientSecret string
+
+	// RefreshToken is the refresh token exchanged for new tokens.
+	RefreshToken string
+
+	// Scopes are the scopes requested.
+	Scopes []string
+
+	// AuthInBody sends the client credentials in the request body, rather than with basic auth.
+	AuthInBody bool
+
+	// HTTPClient is the *http.Client used to make token requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	mutex sync.Mutex
+}
+
+// Token exchanges the refresh token for a new token.
+func (g *RefreshTokenGrant) Token(ctx context.Context) (*Token, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	params := pkgurl.Values{"grant_type": {"refresh_token"}, "refresh_token": {g.RefreshToken}}
+	if len(g.Scopes) > 0 {
+		params.Set("scope", strings.Join(g.Scopes, " "))
+	}
+
+	token, err := requestToken(ctx, g.HTTPClient, g.TokenURL, g.ClientID, g.ClientSecret, g.AuthInBody, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.RefreshToken == "" {
+		token.RefreshToken = g.RefreshToken
+	}
+
+	g.RefreshToken = token.RefreshToken
+	return token, nil
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func requestToken(ctx context.Context, httpClient *http.Client, tokenURL, clientID, clientSecret string, authInBody bool, params pkgurl.Values) (*Token, error) {
+	options := []option.Option[*http.Request]{
+		WithContext(ctx),
+		WithAcceptHeader("application/json"),
+		WithExpectSuccess(),
+	}
+
+	if authInBody {
+		params.Set("client_id", clientID)
+		if clientSecret != "" {
+			params.Set("client_secret", clientSecret)
+		}
+	} else {
+		options = append(options, WithBasicAuth(pkgurl.QueryEscape(clientID), pkgurl.QueryEscape(clientSecret)))
+	}
+
+	options = append(options, WithBodyForm(params))
+
+	request, err := http.NewRequest(http.MethodPost, tokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if request, err = option.Apply(request, options...); err != nil {
+		return nil, err
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	// The token request is sent directly, since ctx may carry the middleware of the request being authorized.
+	requestedAt := time.Now()
+	response, _, err := DecodeJSON[tokenResponse](sendChecked(httpClient, request))
+	if err != nil {
+		return nil, err
+	}
+
+	if response.AccessToken == "" {
+		return nil, fmt.Errorf("token response from %s has no access_token", tokenURL)
+	}
+
+	token := &Token{
+		AccessToken:  response.AccessToken,
+		TokenType:    response.TokenType,
+		RefreshToken: response.RefreshToken,
+	}
+
+	if response.ExpiresIn > 0 {
+		token.Expiry = requestedAt.Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+func sendChecked(httpClient *http.Client, request *http.Request) (*http.Response, error) {
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkStatus(request, response)
+}
+
+// CachedTokenSource caches the tokens of a TokenSource until shortly before they expire.
+// Concurrent calls while a token is being obtained wait for and share its result.
+type CachedTokenSource struct {
+	source       TokenSource
+	expiryLeeway time.Duration
+
+	mutex    sync.Mutex
+	token    *Token
+	inflight *tokenCall
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// NewCachedTokenSource returns a *CachedTokenSource which obtains tokens from source, refreshing them 10 seconds before they expire.
+func NewCachedTokenSource(source TokenSource) *CachedTokenSource {
+	return NewCachedTokenSourceWithLeeway(source, defaultExpiryLeeway)
+}
+
+// NewCachedTokenSourceWithLeeway returns a *CachedTokenSource which obtains tokens from source, refreshing them leeway before they expire.
+func NewCachedTokenSourceWithLeeway(source TokenSource, leeway time.Duration) *CachedTokenSource {
+	return &CachedTokenSource{source: source, expiryLeeway: leeway}
+}
+
+// Token returns the cached token if it is still valid, and otherwise obtains a new one.
+// A new token is obtained in the background, so that a caller giving up on ctx doesn't fail the other callers waiting for it.
+func (s *CachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mutex.Lock()
+	if s.token.valid(s.expiryLeeway) {
+		token := s.token
+		s.mutex.Unlock()
+		return token, nil
+	}
+
+	call := s.inflight
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		s.inflight = call
+		go s.refresh(ctx, call)
+	}
+
+	s.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh obtains a new token for call, using the values of ctx but not its cancellation.
+func (s *CachedTokenSource) refresh(ctx context.Context, call *tokenCall) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			call.token, call.err = nil, fmt.Errorf("token source panicked: %v", recovered)
+		}
+
+		s.mutex.Lock()
+		if call.err == nil {
+			s.token = call.token
+		}
+
+		s.inflight = nil
+		s.mutex.Unlock()
+
+		close(call.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, tokenRefreshTimeout)
+	defer cancel()
+
+	call.token, call.err = s.source.Token(ctx)
+}
+
+// detachedContext carries the values of a context, but not its deadline or cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Invalidate discards token if it is the cached token, so that the next call to Token obtains a new one.
+func (s *CachedTokenSource) Invalidate(token *Token) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// WithOAuth2 authorizes the *http.Request with tokens from source when it is sent. See OAuth2.
+func WithOAuth2(source TokenSource) option.Option[*http.Request] {
+	return WithMiddleware(OAuth2(source))
+}
+
+// OAuth2 returns a Middleware which authorizes requests with tokens from source.
+// If a request is rejected with a 401, the token is invalidated (if source has an Invalidate(*Token) method, as
+// *CachedTokenSource does), and the request is retried once with a new token, provided its body can be replayed.
+func OAuth2(source TokenSource) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		next = orDefaultTransport(next)
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			token, err := source.Token(request.Context())
+			if err != nil {
+				return nil, err
+			}
+
+			authorized := request.Clone(request.Context())
+			authorized.Header.Set("Authorization", token.authorization())
+
+			response, err := next.RoundTrip(authorized)
+			if err != nil || response.StatusCode != http.StatusUnauthorized || !isRewindable(request) {
+				return response, err
+			}
+
+			if invalidator, ok := source.(interface{ Invalidate(*Token) }); ok {
+				invalidator.Invalidate(token)
+			}
+
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+
+			if token, err = source.Token(request.Context()); err != nil {
+				return nil, err
+			}
+
+			retry, err := rewindRequest(authorized, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			retry.Header = retry.Header.Clone()
+			retry.Header.Set("Authorization", token.authorization())
+			return next.RoundTrip(retry)
+		})
+	}
+}
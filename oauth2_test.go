@@ -0,0 +1,273 @@
+package qst_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenServer(t *testing.T, expiresIn int, handle func(r *http.Request)) (*httptest.Server, *int32) {
+	var issued int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if handle != nil {
+			handle(r)
+		}
+
+		if username, password, _ := r.BasicAuth(); r.PostForm.Get("client_secret") != "s3cr3t" && (username != "cereal-app" || password != "s3cr3t") {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+
+		count := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("token-%d", count),
+			"token_type":    "bearer",
+			"refresh_token": fmt.Sprintf("refresh-%d", count),
+			"expires_in":    expiresIn,
+		})
+	}))
+
+	return server, &issued
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	var form map[string][]string
+	tokenServer, _ := newTokenServer(t, 3600, func(r *http.Request) { form = r.PostForm })
+	defer tokenServer.Close()
+
+	grant := &qst.ClientCredentialsGrant{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "cereal-app",
+		ClientSecret: "s3cr3t",
+		Scopes:       []string{"cereals:read", "cereals:write"},
+	}
+
+	token, err := grant.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.AccessToken)
+	assert.Equal(t, "bearer", token.TokenType)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.Expiry, time.Minute)
+	assert.Equal(t, []string{"client_credentials"}, form["grant_type"])
+	assert.Equal(t, []string{"cereals:read cereals:write"}, form["scope"])
+
+	grant.ClientSecret = "wrong"
+	_, err = grant.Token(context.Background())
+	var statusError *qst.StatusError
+	require.ErrorAs(t, err, &statusError)
+	assert.Equal(t, http.StatusUnauthorized, statusError.StatusCode)
+}
+
+func TestRefreshTokenGrant(t *testing.T) {
+	var form map[string][]string
+	tokenServer, _ := newTokenServer(t, 3600, func(r *http.Request) { form = r.PostForm })
+	defer tokenServer.Close()
+
+	grant := &qst.RefreshTokenGrant{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "cereal-app",
+		ClientSecret: "s3cr3t",
+		RefreshToken: "refresh-0",
+		AuthInBody:   true,
+	}
+
+	token, err := grant.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.AccessToken)
+	assert.Equal(t, []string{"refresh_token"}, form["grant_type"])
+	assert.Equal(t, []string{"refresh-0"}, form["refresh_token"])
+	assert.Equal(t, []string{"cereal-app"}, form["client_id"])
+
+	_, err = grant.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"refresh-1"}, form["refresh_token"])
+	assert.Equal(t, "refresh-2", grant.RefreshToken)
+}
+
+func TestCachedTokenSource(t *testing.T) {
+	t.Run("caches until expiry", func(t *testing.T) {
+		tokenServer, issued := newTokenServer(t, 3600, nil)
+		defer tokenServer.Close()
+
+		source := qst.NewCachedTokenSource(&qst.ClientCredentialsGrant{TokenURL: tokenServer.URL, ClientID: "cereal-app", ClientSecret: "s3cr3t"})
+		for i := 0; i < 3; i++ {
+			token, err := source.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "token-1", token.AccessToken)
+		}
+
+		assert.EqualValues(t, 1, atomic.LoadInt32(issued))
+	})
+
+	t.Run("refreshes within leeway of expiry", func(t *testing.T) {
+		tokenServer, issued := newTokenServer(t, 60, nil)
+		defer tokenServer.Close()
+
+		source := qst.NewCachedTokenSourceWithLeeway(&qst.ClientCredentialsGrant{TokenURL: tokenServer.URL, ClientID: "cereal-app", ClientSecret: "s3cr3t"}, time.Minute)
+		for i := 1; i <= 2; i++ {
+			token, err := source.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("token-%d", i), token.AccessToken)
+		}
+
+		assert.EqualValues(t, 2, atomic.LoadInt32(issued))
+	})
+
+	t.Run("shares concurrent refreshes", func(t *testing.T) {
+		tokenServer, issued := newTokenServer(t, 3600, func(*http.Request) { time.Sleep(50 * time.Millisecond) })
+		defer tokenServer.Close()
+
+		source := qst.NewCachedTokenSource(&qst.ClientCredentialsGrant{TokenURL: tokenServer.URL, ClientID: "cereal-app", ClientSecret: "s3cr3t"})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token, err := source.Token(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, "token-1", token.AccessToken)
+			}()
+		}
+
+		wg.Wait()
+		assert.EqualValues(t, 1, atomic.LoadInt32(issued))
+	})
+
+	t.Run("a canceled caller doesn't fail the others", func(t *testing.T) {
+		tokenServer, issued := newTokenServer(t, 3600, func(*http.Request) { time.Sleep(50 * time.Millisecond) })
+		defer tokenServer.Close()
+
+		source := qst.NewCachedTokenSource(&qst.ClientCredentialsGrant{TokenURL: tokenServer.URL, ClientID: "cereal-app", ClientSecret: "s3cr3t"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		leaderErr := make(chan error)
+		go func() {
+			_, err := source.Token(ctx)
+			leaderErr <- err
+		}()
+
+		time.Sleep(5 * time.Millisecond)
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token.AccessToken)
+		assert.ErrorIs(t, <-leaderErr, context.DeadlineExceeded)
+		assert.EqualValues(t, 1, atomic.LoadInt32(issued))
+	})
+
+	t.Run("a panicking source doesn't block later calls", func(t *testing.T) {
+		calls := 0
+		source := qst.NewCachedTokenSource(tokenSourceFunc(func(context.Context) (*qst.Token, error) {
+			calls++
+			if calls == 1 {
+				panic("out of milk")
+			}
+
+			return &qst.Token{AccessToken: "token-2"}, nil
+		}))
+
+		_, err := source.Token(context.Background())
+		assert.EqualError(t, err, "token source panicked: out of milk")
+
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token-2", token.AccessToken)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		tokenServer, _ := newTokenServer(t, 3600, nil)
+		defer tokenServer.Close()
+
+		source := qst.NewCachedTokenSource(&qst.ClientCredentialsGrant{TokenURL: tokenServer.URL, ClientID: "cereal-app", ClientSecret: "s3cr3t"})
+		stale, err := source.Token(context.Background())
+		require.NoError(t, err)
+
+		source.Invalidate(stale)
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token-2", token.AccessToken)
+
+		source.Invalidate(stale)
+		token, err = source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token-2", token.AccessToken)
+	})
+}
+
+func TestWithOAuth2(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 3600, nil)
+	defer tokenServer.Close()
+
+	var accepted atomic.Value
+	accepted.Store("Bearer token-1")
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodies = append(bodies, readAll(t, r.Body))
+		if r.Header.Get("Authorization") != accepted.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	source := qst.NewCachedTokenSource(&qst.ClientCredentialsGrant{TokenURL: tokenServer.URL, ClientID: "cereal-app", ClientSecret: "s3cr3t"})
+	client := qst.NewClient(http.DefaultClient, server.URL, qst.WithOAuth2(source))
+
+	response, err := client.Post("/cereals", qst.WithBodyString("Life"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.EqualValues(t, 1, atomic.LoadInt32(issued))
+
+	t.Run("retries once on 401 with a new token", func(t *testing.T) {
+		bodies = nil
+		accepted.Store("Bearer token-2")
+
+		response, err := client.Post("/cereals", qst.WithBodyString("Life"))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		assert.Equal(t, []string{"Life", "Life"}, bodies)
+		assert.EqualValues(t, 2, atomic.LoadInt32(issued))
+	})
+
+	t.Run("doesn't retry a body that can't be replayed", func(t *testing.T) {
+		bodies = nil
+		accepted.Store("Bearer token-3")
+
+		response, err := client.Post("/cereals", qst.WithBodyReader(struct{ io.Reader }{strings.NewReader("Life")}))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+		assert.Equal(t, []string{"Life"}, bodies)
+	})
+
+	t.Run("token errors", func(t *testing.T) {
+		source := qst.NewCachedTokenSource(&qst.ClientCredentialsGrant{TokenURL: tokenServer.URL, ClientID: "cereal-app", ClientSecret: "wrong"})
+		_, err := qst.Get(server.URL, qst.WithOAuth2(source))
+		var statusError *qst.StatusError
+		require.ErrorAs(t, err, &statusError)
+		assert.Equal(t, http.StatusUnauthorized, statusError.StatusCode)
+	})
+}
+
+type tokenSourceFunc func(ctx context.Context) (*qst.Token, error)
+
+func (f tokenSourceFunc) Token(ctx context.Context) (*qst.Token, error) {
+	return f(ctx)
+}
@@ -5,17 +5,26 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
 	pkgurl "net/url"
 	pkgpath "path"
+	"strings"
 
 	"github.com/broothie/option"
 )
 
+// WithMethod applies the method to the *http.Request.
+func WithMethod(method string) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		request.Method = method
+		return request, nil
+	})
+}
+
 // WithRawURL applies the URL to the *http.Request.
 func WithRawURL(url *pkgurl.URL) option.Option[*http.Request] {
 	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
@@ -86,6 +95,81 @@ func WithPath(segments ...string) option.Option[*http.Request] {
 	})
 }
 
+// ErrPathTraversal is returned by WithStrictPathSegments when a segment is "." or "..".
+var ErrPathTraversal = errors.New("path traversal segment")
+
+// WithPathSegments escapes each segment with url.PathEscape, and appends the result to the *http.Request URL,
+// keeping URL.RawPath in sync so that escaped slashes are preserved.
+// Unlike WithPath, empty segments are kept. "." segments are dropped and ".." segments remove the preceding segment.
+func WithPathSegments(segments ...string) option.Option[*http.Request] {
+	return withPathSegments(segments, false)
+}
+
+// WithStrictPathSegments is like WithPathSegments, but fails with ErrPathTraversal if a segment is "." or "..".
+func WithStrictPathSegments(segments ...string) option.Option[*http.Request] {
+	return withPathSegments(segments, true)
+}
+
+func withPathSegments(segments []string, strict bool) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		if len(segments) == 0 {
+			return request, nil
+		}
+
+		escaped := strings.Split(strings.TrimSuffix(request.URL.EscapedPath(), "/"), "/")
+		for _, segment := range segments {
+			switch segment {
+			case ".", "..":
+				if strict {
+					return nil, fmt.Errorf("%w %q", ErrPathTraversal, segment)
+				}
+
+				if segment == ".." && len(escaped) > 1 {
+					escaped = escaped[:len(escaped)-1]
+				}
+
+			default:
+				escaped = append(escaped, pkgurl.PathEscape(segment))
+			}
+		}
+
+		if err := setEscapedPath(request.URL, strings.Join(escaped, "/")); err != nil {
+			return nil, err
+		}
+
+		return request, nil
+	})
+}
+
+// appendEscapedPath appends an already escaped path to the URL path.
+func appendEscapedPath(url *pkgurl.URL, escapedPath string) error {
+	if escapedPath == "" {
+		return nil
+	}
+
+	return setEscapedPath(url, fmt.Sprintf("%s/%s", strings.TrimSuffix(url.EscapedPath(), "/"), strings.TrimPrefix(escapedPath, "/")))
+}
+
+// setEscapedPath sets the URL path from an already escaped path, keeping URL.RawPath in sync.
+func setEscapedPath(url *pkgurl.URL, escapedPath string) error {
+	if !strings.HasPrefix(escapedPath, "/") {
+		escapedPath = fmt.Sprintf("/%s", escapedPath)
+	}
+
+	path, err := pkgurl.PathUnescape(escapedPath)
+	if err != nil {
+		return err
+	}
+
+	url.Path = path
+	url.RawPath = ""
+	if url.EscapedPath() != escapedPath {
+		url.RawPath = escapedPath
+	}
+
+	return nil
+}
+
 // WithQuery applies a key/value pair to the query parameters of the *http.Request.
 func WithQuery(key, value string) option.Option[*http.Request] {
 	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
@@ -201,26 +285,71 @@ func WithContextValue(key, value interface{}) option.Option[*http.Request] {
 }
 
 // WithBody applies an io.ReadCloser to the *http.Request body.
+// Since the body can only be read once, any previously applied GetBody and ContentLength are cleared.
 func WithBody(body io.ReadCloser) option.Option[*http.Request] {
 	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
 		request.Body = body
+		request.GetBody = nil
+		request.ContentLength = 0
 		return request, nil
 	})
 }
 
 // WithBodyReader applies an io.Reader to the *http.Request body.
+// As with http.NewRequest, a *bytes.Buffer, *bytes.Reader, or *strings.Reader body also sets ContentLength and GetBody.
 func WithBodyReader(body io.Reader) option.Option[*http.Request] {
-	return WithBody(ioutil.NopCloser(body))
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		switch body := body.(type) {
+		case *bytes.Buffer:
+			return WithBodyBytes(body.Bytes()).Apply(request)
+
+		case *bytes.Reader:
+			snapshot := *body
+			return withReplayableBody(int64(body.Len()), func() io.Reader {
+				r := snapshot
+				return &r
+			}).Apply(request)
+
+		case *strings.Reader:
+			snapshot := *body
+			return withReplayableBody(int64(body.Len()), func() io.Reader {
+				r := snapshot
+				return &r
+			}).Apply(request)
+
+		default:
+			return WithBody(ioutil.NopCloser(body)).Apply(request)
+		}
+	})
 }
 
-// WithBodyBytes applies a slice of bytes to the *http.Request body.
+// WithBodyBytes applies a slice of bytes to the *http.Request body, setting ContentLength and GetBody.
 func WithBodyBytes(body []byte) option.Option[*http.Request] {
-	return WithBodyReader(bytes.NewBuffer(body))
+	return withReplayableBody(int64(len(body)), func() io.Reader { return bytes.NewReader(body) })
 }
 
-// WithBodyString applies a string to the *http.Request body.
+// WithBodyString applies a string to the *http.Request body, setting ContentLength and GetBody.
 func WithBodyString(body string) option.Option[*http.Request] {
-	return WithBodyBytes([]byte(body))
+	return withReplayableBody(int64(len(body)), func() io.Reader { return strings.NewReader(body) })
+}
+
+// withReplayableBody applies a body of a known length which can be read again by calling newReader.
+func withReplayableBody(length int64, newReader func() io.Reader) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		getBody := func() (io.ReadCloser, error) {
+			if length == 0 {
+				return http.NoBody, nil
+			}
+
+			return ioutil.NopCloser(newReader()), nil
+		}
+
+		body, _ := getBody()
+		request.Body = body
+		request.GetBody = getBody
+		request.ContentLength = length
+		return request, nil
+	})
 }
 
 // WithBodyForm URL-encodes multiple key/value pairs and applies the result to the *http.Request body.
@@ -244,7 +373,7 @@ func WithBodyJSON(v interface{}) option.Option[*http.Request] {
 
 		return option.Apply(request,
 			WithContentTypeHeader("application/json"),
-			WithBodyReader(body),
+			WithBodyBytes(body.Bytes()),
 		)
 	})
 }
@@ -259,23 +388,13 @@ func WithBodyXML(v interface{}) option.Option[*http.Request] {
 
 		return option.Apply(request,
 			WithContentTypeHeader("application/xml"),
-			WithBodyReader(body),
+			WithBodyBytes(body.Bytes()),
 		)
 	})
 }
 
 // WithDump writes the request to w.
+// If the body can be replayed with GetBody, it is dumped from a copy and left unread.
 func WithDump(w io.Writer) option.Option[*http.Request] {
-	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
-		dump, err := httputil.DumpRequest(request, true)
-		if err != nil {
-			return nil, err
-		}
-
-		if _, err := w.Write(dump); err != nil {
-			return nil, err
-		}
-
-		return request, nil
-	})
+	return WithDumpOptions(w, DumpOptions{})
 }
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/broothie/option"
 	"github.com/broothie/qst"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOptions_errors(t *testing.T) {
@@ -57,6 +61,74 @@ func ExampleWithPath() {
 	// Output: /api/cereals/1234/variants/frosted
 }
 
+func TestWithPathSegments(t *testing.T) {
+	testCases := map[string]struct {
+		url      string
+		segments []string
+		path     string
+		escaped  string
+	}{
+		"escapes segments": {
+			url:      "https://breakfast.com/api",
+			segments: []string{"cereals", "frosted/flakes", "a?b#c d"},
+			path:     "/api/cereals/frosted/flakes/a?b#c d",
+			escaped:  "/api/cereals/frosted%2Fflakes/a%3Fb%23c%20d",
+		},
+		"keeps empty segments": {
+			url:      "https://breakfast.com/api/",
+			segments: []string{"cereals", "", "1234"},
+			path:     "/api/cereals//1234",
+			escaped:  "/api/cereals//1234",
+		},
+		"keeps existing escaped path": {
+			url:      "https://breakfast.com/api/a%2Fb",
+			segments: []string{"c"},
+			path:     "/api/a/b/c",
+			escaped:  "/api/a%2Fb/c",
+		},
+		"resolves dot segments": {
+			url:      "https://breakfast.com/api/v1",
+			segments: []string{"..", "v2", ".", "cereals"},
+			path:     "/api/v2/cereals",
+			escaped:  "/api/v2/cereals",
+		},
+		"doesn't traverse above root": {
+			url:      "https://breakfast.com/api",
+			segments: []string{"..", "..", "cereals"},
+			path:     "/cereals",
+			escaped:  "/cereals",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			request, err := qst.NewGet(tc.url, qst.WithPathSegments(tc.segments...))
+			require.NoError(t, err)
+			assert.Equal(t, tc.path, request.URL.Path)
+			assert.Equal(t, tc.escaped, request.URL.EscapedPath())
+		})
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		request, err := qst.NewGet("https://breakfast.com/api", qst.WithStrictPathSegments("cereals", "a/b"))
+		require.NoError(t, err)
+		assert.Equal(t, "https://breakfast.com/api/cereals/a%2Fb", request.URL.String())
+
+		_, err = qst.NewGet("https://breakfast.com/api", qst.WithStrictPathSegments("cereals", ".."))
+		assert.ErrorIs(t, err, qst.ErrPathTraversal)
+		assert.EqualError(t, err, `failed to apply option 0: path traversal segment ".."`)
+	})
+}
+
+func ExampleWithPathSegments() {
+	request, _ := qst.NewGet("https://breakfast.com/api",
+		qst.WithPathSegments("cereals", "frosted/flakes"),
+	)
+
+	fmt.Println(request.URL)
+	// Output: https://breakfast.com/api/cereals/frosted%2Fflakes
+}
+
 func ExampleWithUsername() {
 	request, _ := qst.NewGet("https://breakfast.com/api/cereals",
 		qst.WithUsername("TonyTheTiger"),
@@ -287,6 +359,88 @@ func TestDump(t *testing.T) {
 	assert.Equal(t, expected, buffer.String())
 }
 
+func TestBodyReplayable(t *testing.T) {
+	testCases := map[string]option.Option[*http.Request]{
+		"bytes":          qst.WithBodyBytes([]byte("Part of a complete breakfast.")),
+		"string":         qst.WithBodyString("Part of a complete breakfast."),
+		"bytes.Buffer":   qst.WithBodyReader(bytes.NewBufferString("Part of a complete breakfast.")),
+		"bytes.Reader":   qst.WithBodyReader(bytes.NewReader([]byte("Part of a complete breakfast."))),
+		"strings.Reader": qst.WithBodyReader(strings.NewReader("Part of a complete breakfast.")),
+	}
+
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			request, err := qst.NewPost("https://breakfast.com/api/cereals", body)
+			require.NoError(t, err)
+			assert.EqualValues(t, len("Part of a complete breakfast."), request.ContentLength)
+			require.NotNil(t, request.GetBody)
+
+			for i := 0; i < 2; i++ {
+				replayed, err := request.GetBody()
+				require.NoError(t, err)
+
+				got, err := ioutil.ReadAll(replayed)
+				require.NoError(t, err)
+				assert.Equal(t, "Part of a complete breakfast.", string(got))
+			}
+
+			got, err := ioutil.ReadAll(request.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "Part of a complete breakfast.", string(got))
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/cereals", qst.WithBodyString(""))
+		require.NoError(t, err)
+		assert.Equal(t, http.NoBody, request.Body)
+		assert.Zero(t, request.ContentLength)
+	})
+
+	t.Run("unknown reader", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/cereals",
+			qst.WithBodyString("Part of a complete breakfast."),
+			qst.WithBodyReader(broken{}),
+		)
+
+		require.NoError(t, err)
+		assert.Nil(t, request.GetBody)
+		assert.Zero(t, request.ContentLength)
+	})
+
+	t.Run("survives redirect", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/old" {
+				http.Redirect(w, r, "/new", http.StatusPermanentRedirect)
+				return
+			}
+
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, `{"name":"Life"}`+"\n", string(body))
+			assert.EqualValues(t, len(body), r.ContentLength)
+		}))
+		defer server.Close()
+
+		response, err := qst.Post(server.URL+"/old", qst.WithBodyJSON(map[string]string{"name": "Life"}))
+		require.NoError(t, err)
+		assert.Equal(t, "/new", response.Request.URL.Path)
+	})
+}
+
+func TestDump_doesNotConsumeBody(t *testing.T) {
+	var buffer bytes.Buffer
+	request, err := qst.NewPost("https://breakfast.com/api/cereals",
+		qst.WithBodyString("Part of a complete breakfast."),
+		qst.WithDump(&buffer),
+	)
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(request.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Part of a complete breakfast.", string(body))
+	assert.Contains(t, buffer.String(), "Part of a complete breakfast.")
+}
+
 type broken struct{}
 
 func (broken) Write([]byte) (int, error) {
@@ -0,0 +1,247 @@
+package qst
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+// RateLimiter throttles requests with a token bucket per key, by default the request's host.
+//
+// Buckets adapt to the rate limit headers of responses: "RateLimit-Remaining" and "RateLimit-Reset", their
+// "X-RateLimit-" equivalents, the combined "RateLimit" header, and "Retry-After" on 429 and 503 responses. When a
+// server reports that no requests remain, requests to the bucket wait until the reported reset.
+type RateLimiter struct {
+	// Rate is the number of requests per second allowed for each key. If zero, requests are only throttled by rate limit headers.
+	Rate float64
+
+	// Burst is the number of requests that can be made at once for each key. Defaults to 1.
+	Burst int
+
+	// Key returns the bucket key of a request. If nil, the request's host is used.
+	Key func(*http.Request) string
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// NewRateLimiter returns a *RateLimiter allowing rate requests per second per host, with bursts of up to burst requests.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{Rate: rate, Burst: burst}
+}
+
+// WithRateLimit throttles the *http.Request with limiter. The limiter should be shared between requests, e.g. by
+// reusing the option or adding limiter.RoundTripper to Client.Middleware.
+func WithRateLimit(limiter *RateLimiter) option.Option[*http.Request] {
+	return WithMiddleware(limiter.RoundTripper)
+}
+
+// RoundTripper wraps next, waiting for each request to be allowed before sending it.
+func (l *RateLimiter) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		if err := l.Wait(request); err != nil {
+			return nil, err
+		}
+
+		response, err := next.RoundTrip(request)
+		if err == nil {
+			l.adapt(request, response)
+		}
+
+		return response, err
+	})
+}
+
+// Wait blocks until request is allowed, or its context is done.
+func (l *RateLimiter) Wait(request *http.Request) error {
+	key := l.key(request)
+	delay := l.reserve(key)
+	if delay <= 0 {
+		return nil
+	}
+
+	if err := sleep(request.Context(), delay); err != nil {
+		l.cancel(key)
+		return err
+	}
+
+	return nil
+}
+
+// reserve takes a token from the bucket for key, returning how long to wait until it's available.
+func (l *RateLimiter) reserve(key string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket := l.bucket(key, now)
+	bucket.tokens--
+
+	var delay time.Duration
+	if bucket.tokens < 0 {
+		if l.Rate <= 0 {
+			bucket.tokens = 0
+		} else {
+			delay = time.Duration(-bucket.tokens / l.Rate * float64(time.Second))
+		}
+	}
+
+	if blocked := bucket.blockedUntil.Sub(now); blocked > delay {
+		delay = blocked
+	}
+
+	return delay
+}
+
+// cancel returns a reserved token to the bucket for key.
+func (l *RateLimiter) cancel(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.bucket(key, time.Now()).tokens++
+}
+
+// adapt updates the bucket of request with the rate limit headers of response.
+func (l *RateLimiter) adapt(request *http.Request, response *http.Response) {
+	now := time.Now()
+	remaining, hasRemaining, reset, hasReset := parseRateLimitHeaders(response.Header, now)
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			remaining, hasRemaining, reset, hasReset = 0, true, retryAfter, true
+		}
+	}
+
+	if !hasRemaining {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket := l.bucket(l.key(request), now)
+	if float64(remaining) < bucket.tokens {
+		bucket.tokens = float64(remaining)
+	}
+
+	if remaining <= 0 && hasReset {
+		bucket.blockedUntil = now.Add(reset)
+	}
+}
+
+// bucket returns the bucket for key, refilled up to now. It must be called with the mutex held.
+func (l *RateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = bucket
+		return bucket
+	}
+
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * l.Rate
+		if bucket.tokens > burst {
+			bucket.tokens = burst
+		}
+
+		bucket.last = now
+	}
+
+	return bucket
+}
+
+func (l *RateLimiter) key(request *http.Request) string {
+	if l.Key != nil {
+		return l.Key(request)
+	}
+
+	if request.Host != "" {
+		return request.Host
+	}
+
+	return request.URL.Host
+}
+
+// parseRateLimitHeaders returns the remaining requests and the time until reset reported by header.
+func parseRateLimitHeaders(header http.Header, now time.Time) (int, bool, time.Duration, bool) {
+	remainingValue := firstHeader(header, "RateLimit-Remaining", "X-RateLimit-Remaining")
+	resetValue := firstHeader(header, "RateLimit-Reset", "X-RateLimit-Reset")
+
+	// The "RateLimit" header is either a dictionary, e.g. "limit=10, remaining=0, reset=1", or a list of policies with
+	// parameters, e.g. `"default";r=0;t=1`, in which case the policy with the fewest remaining requests is used.
+	fewestRemaining := -1
+	for _, member := range strings.Split(header.Get("RateLimit"), ",") {
+		var policyRemaining, policyReset string
+		for _, param := range strings.Split(member, ";") {
+			name, value := param, ""
+			if i := strings.Index(param, "="); i >= 0 {
+				name, value = param[:i], param[i+1:]
+			}
+
+			switch strings.TrimSpace(name) {
+			case "remaining":
+				remainingValue = strings.TrimSpace(value)
+			case "reset":
+				resetValue = strings.TrimSpace(value)
+			case "r":
+				policyRemaining = strings.TrimSpace(value)
+			case "t":
+				policyReset = strings.TrimSpace(value)
+			}
+		}
+
+		if remaining, err := strconv.Atoi(policyRemaining); err == nil && (fewestRemaining < 0 || remaining < fewestRemaining) {
+			fewestRemaining = remaining
+			remainingValue, resetValue = policyRemaining, policyReset
+		}
+	}
+
+	remaining, err := strconv.Atoi(remainingValue)
+	hasRemaining := err == nil
+
+	resetSeconds, err := strconv.ParseInt(resetValue, 10, 64)
+	if err != nil || resetSeconds < 0 {
+		return remaining, hasRemaining, 0, false
+	}
+
+	// Some servers send the reset as a Unix timestamp rather than a number of seconds.
+	if resetSeconds > 1e9 {
+		reset := time.Unix(resetSeconds, 0).Sub(now)
+		if reset < 0 {
+			reset = 0
+		}
+
+		return remaining, hasRemaining, reset, true
+	}
+
+	return remaining, hasRemaining, time.Duration(resetSeconds) * time.Second, true
+}
+
+func firstHeader(header http.Header, names ...string) string {
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,103 @@
+package qst_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range r.URL.Query() {
+			w.Header()[key] = values
+		}
+
+		if r.URL.Query().Get("Retry-After") != "" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer otherServer.Close()
+
+	t.Run("throttles to the rate after a burst", func(t *testing.T) {
+		limiter := qst.NewRateLimiter(20, 2)
+
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			_, err := qst.Get(server.URL, qst.WithRateLimit(limiter))
+			require.NoError(t, err)
+		}
+
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+
+	t.Run("per host", func(t *testing.T) {
+		limiter := qst.NewRateLimiter(0.1, 1)
+
+		start := time.Now()
+		_, err := qst.Get(server.URL, qst.WithRateLimit(limiter))
+		require.NoError(t, err)
+
+		_, err = qst.Get(otherServer.URL, qst.WithRateLimit(limiter))
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("custom key", func(t *testing.T) {
+		limiter := qst.NewRateLimiter(0.1, 1)
+		limiter.Key = func(*http.Request) string { return "everything" }
+
+		_, err := qst.Get(server.URL, qst.WithRateLimit(limiter))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = qst.Get(otherServer.URL, qst.WithContext(ctx), qst.WithRateLimit(limiter))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("respects the request context", func(t *testing.T) {
+		limiter := qst.NewRateLimiter(0.1, 1)
+		_, err := qst.Get(server.URL, qst.WithRateLimit(limiter))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err = qst.Get(server.URL, qst.WithContext(ctx), qst.WithRateLimit(limiter))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	testCases := map[string]qst.WithQueries{
+		"X-RateLimit headers":         {"X-RateLimit-Remaining": {"0"}, "X-RateLimit-Reset": {"1"}},
+		"RateLimit headers":           {"RateLimit-Remaining": {"0"}, "RateLimit-Reset": {"1"}},
+		"RateLimit header":            {"RateLimit": {"limit=10, remaining=0, reset=1"}},
+		"structured RateLimit header": {"RateLimit": {`"burst";r=5;t=1, "default";r=0;t=1`}},
+		"Retry-After":                 {"Retry-After": {"1"}},
+	}
+
+	for name, headers := range testCases {
+		t.Run("adapts to "+name, func(t *testing.T) {
+			limiter := qst.NewRateLimiter(0, 1)
+
+			_, err := qst.Get(server.URL, qst.WithRateLimit(limiter), qst.WithQueries(headers))
+			require.NoError(t, err)
+
+			start := time.Now()
+			_, err = qst.Get(server.URL, qst.WithRateLimit(limiter))
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+		})
+	}
+}
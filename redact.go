@@ -0,0 +1,195 @@
+package qst
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	pkgurl "net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// Redaction describes which parts of a request or response are secret, and are redacted when dumped.
+// Passwords in the URL are always redacted.
+type Redaction struct {
+	// Headers are the names of headers whose values are redacted.
+	// The scheme of authorization headers and the names of cookies are kept.
+	Headers []string
+
+	// QueryKeys are the names of query parameters and URL-encoded form fields whose values are redacted.
+	QueryKeys []string
+
+	// JSONFields are the dot-separated paths of JSON body fields whose values are redacted, e.g. "user.password".
+	// Arrays are traversed, so "users.password" redacts the password of every element of "users".
+	JSONFields []string
+}
+
+// DefaultRedaction redacts common credential headers, query parameters, and JSON fields.
+var DefaultRedaction = Redaction{
+	Headers:    []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"},
+	QueryKeys:  []string{"access_token", "api_key", "apikey", "client_secret", "password", "secret", "token"},
+	JSONFields: []string{"access_token", "client_secret", "password", "refresh_token", "secret", "token"},
+}
+
+// redactHeader returns a copy of header with secret values redacted.
+func (r *Redaction) redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range r.Headers {
+		name = http.CanonicalHeaderKey(name)
+		for i, value := range header[name] {
+			switch name {
+			case "Authorization", "Proxy-Authorization":
+				header[name][i] = redactCredentials(value)
+			case "Cookie":
+				header[name][i] = redactCookies(value)
+			case "Set-Cookie":
+				header[name][i] = redactSetCookie(value)
+			default:
+				header[name][i] = redacted
+			}
+		}
+	}
+
+	return header
+}
+
+// redactURL returns a copy of url with the password and secret query values redacted.
+func (r *Redaction) redactURL(url *pkgurl.URL) *pkgurl.URL {
+	redactedURL := *url
+	if password, ok := url.User.Password(); ok && password != "" {
+		redactedURL.User = pkgurl.UserPassword(url.User.Username(), redacted)
+	}
+
+	if url.RawQuery != "" {
+		if query, err := pkgurl.ParseQuery(url.RawQuery); err == nil && r.redactValues(query) {
+			redactedURL.RawQuery = query.Encode()
+		}
+	}
+
+	return &redactedURL
+}
+
+// redactBody returns body with secret JSON fields or form values redacted, according to contentType.
+func (r *Redaction) redactBody(contentType string, body []byte) []byte {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || len(body) == 0 {
+		return body
+	}
+
+	switch {
+	case isJSONMediaType(mediaType) && len(r.JSONFields) > 0:
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		var document interface{}
+		if err := decoder.Decode(&document); err != nil {
+			return body
+		}
+
+		changed := false
+		for _, field := range r.JSONFields {
+			changed = redactJSONPath(document, strings.Split(field, ".")) || changed
+		}
+
+		if !changed {
+			return body
+		}
+
+		redactedBody, err := json.Marshal(document)
+		if err != nil {
+			return body
+		}
+
+		return redactedBody
+
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := pkgurl.ParseQuery(string(body))
+		if err != nil || !r.redactValues(form) {
+			return body
+		}
+
+		return []byte(form.Encode())
+	}
+
+	return body
+}
+
+// redactValues redacts the secret values of values in place, and reports whether any were redacted.
+func (r *Redaction) redactValues(values pkgurl.Values) bool {
+	changed := false
+	for key := range values {
+		for _, secret := range r.QueryKeys {
+			if strings.EqualFold(key, secret) {
+				for i := range values[key] {
+					values[key][i] = redacted
+				}
+
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+// redactJSONPath redacts the value at path within a decoded JSON document, and reports whether any were redacted.
+func redactJSONPath(document interface{}, path []string) bool {
+	switch document := document.(type) {
+	case map[string]interface{}:
+		value, ok := document[path[0]]
+		if !ok {
+			return false
+		}
+
+		if len(path) == 1 {
+			document[path[0]] = redacted
+			return true
+		}
+
+		return redactJSONPath(value, path[1:])
+
+	case []interface{}:
+		changed := false
+		for _, element := range document {
+			changed = redactJSONPath(element, path) || changed
+		}
+
+		return changed
+	}
+
+	return false
+}
+
+// redactCredentials redacts the credentials of an authorization header value, leaving the scheme.
+func redactCredentials(value string) string {
+	if scheme, _, found := strings.Cut(value, " "); found {
+		return fmt.Sprintf("%s %s", scheme, redacted)
+	}
+
+	return redacted
+}
+
+// redactCookies redacts the values of a "Cookie" header value, leaving the names.
+func redactCookies(value string) string {
+	cookies := strings.Split(value, ";")
+	for i, cookie := range cookies {
+		name, _, _ := strings.Cut(strings.TrimSpace(cookie), "=")
+		cookies[i] = fmt.Sprintf("%s=%s", name, redacted)
+	}
+
+	return strings.Join(cookies, "; ")
+}
+
+// redactSetCookie redacts the value of a "Set-Cookie" header value, leaving the name and attributes.
+func redactSetCookie(value string) string {
+	pair, attributes, found := strings.Cut(value, ";")
+	name, _, _ := strings.Cut(strings.TrimSpace(pair), "=")
+	if !found {
+		return fmt.Sprintf("%s=%s", name, redacted)
+	}
+
+	return fmt.Sprintf("%s=%s;%s", name, redacted, attributes)
+}
@@ -0,0 +1,286 @@
+package qst
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 100 * time.Millisecond
+	defaultRetryMaxDelay    = 10 * time.Second
+
+	// maxRetryDrainSize is the most of a discarded response body read so that its connection can be reused.
+	maxRetryDrainSize = 4 << 10
+)
+
+var defaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy describes when and how often a request is retried.
+// Only idempotent requests are retried: POST and PATCH requests are retried only if they have an "Idempotency-Key" header.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first. Defaults to 3.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubled for each subsequent retry. Defaults to 100ms.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the exponential backoff delay. Defaults to 10s.
+	// A response whose "Retry-After" delay is longer than MaxDelay isn't retried, and is returned instead.
+	MaxDelay time.Duration
+
+	// RetryableStatusCodes are the response status codes which are retried. Defaults to 429, 502, 503, and 504.
+	RetryableStatusCodes []int
+
+	// OnAttempt, if set, is called after each attempt.
+	OnAttempt func(RetryAttempt)
+}
+
+// RetryAttempt describes the outcome of a single attempt of a request.
+type RetryAttempt struct {
+	// Request is the request that was attempted.
+	Request *http.Request
+
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int
+
+	// Response is the response of the attempt, if there was one.
+	Response *http.Response
+
+	// Err is the error of the attempt, if there was one.
+	Err error
+
+	// Retry reports whether the request will be retried.
+	Retry bool
+
+	// Delay is the time waited before the next attempt.
+	Delay time.Duration
+}
+
+type retryPolicyKey struct{}
+
+// WithRetry applies a *RetryPolicy to the *http.Request, overriding any set on the *Client. A nil policy disables retries.
+func WithRetry(policy *RetryPolicy) option.Option[*http.Request] {
+	return WithContextValue(retryPolicyKey{}, policy)
+}
+
+// RoundTripper wraps next so that requests are retried according to the *RetryPolicy.
+func (p *RetryPolicy) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		for attempt := 1; ; attempt++ {
+			attemptRequest, err := rewindRequest(request, attempt)
+			if err != nil {
+				return nil, err
+			}
+
+			response, err := next.RoundTrip(attemptRequest)
+			delay, retry := p.retryDelay(request, attempt, response, err)
+			if p.OnAttempt != nil {
+				p.OnAttempt(RetryAttempt{
+					Request:  attemptRequest,
+					Attempt:  attempt,
+					Response: response,
+					Err:      err,
+					Retry:    retry,
+					Delay:    delay,
+				})
+			}
+
+			if !retry {
+				return response, err
+			}
+
+			if response != nil {
+				_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, maxRetryDrainSize))
+				response.Body.Close()
+			}
+
+			if err := sleep(request.Context(), delay); err != nil {
+				return nil, err
+			}
+		}
+	})
+}
+
+func (p *RetryPolicy) retryDelay(request *http.Request, attempt int, response *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.maxAttempts() || !isIdempotent(request) || !isRewindable(request) || request.Context().Err() != nil {
+		return 0, false
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+
+		return p.backoff(attempt), true
+	}
+
+	if !p.isRetryableStatusCode(response.StatusCode) {
+		return 0, false
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			return delay, delay <= p.maxDelay()
+		}
+	}
+
+	return p.backoff(attempt), true
+}
+
+// backoff returns an exponential backoff delay, with jitter of up to half the delay.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay, maxDelay := p.baseDelay(), p.maxDelay()
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+
+	return time.Duration(half + rand.Int63n(half+1)) // #nosec G404 -- jitter doesn't need a secure source
+}
+
+func (p *RetryPolicy) isRetryableStatusCode(statusCode int) bool {
+	codes := p.RetryableStatusCodes
+	if codes == nil {
+		codes = defaultRetryableStatusCodes
+	}
+
+	for _, code := range codes {
+		if statusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return defaultRetryMaxAttempts
+	}
+
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) baseDelay() time.Duration {
+	if p.BaseDelay <= 0 {
+		return defaultRetryBaseDelay
+	}
+
+	return p.BaseDelay
+}
+
+func (p *RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return defaultRetryMaxDelay
+	}
+
+	return p.MaxDelay
+}
+
+func isIdempotent(request *http.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, hasIdempotencyKey := request.Header["Idempotency-Key"]
+	_, hasXIdempotencyKey := request.Header["X-Idempotency-Key"]
+	return hasIdempotencyKey || hasXIdempotencyKey
+}
+
+func isRewindable(request *http.Request) bool {
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+// rewindRequest returns request for the first attempt, and a copy of request with a fresh body for subsequent attempts.
+func rewindRequest(request *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || request.Body == nil || request.Body == http.NoBody {
+		return request, nil
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	rewound := *request
+	rewound.Body = body
+	return &rewound, nil
+}
+
+// parseRetryAfter parses a "Retry-After" header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(retryAfter string) (time.Duration, bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(retryAfter)
+	if err != nil {
+		return 0, false
+	}
+
+	delay := time.Until(date)
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay, true
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+
+	case <-timer.C:
+		return nil
+	}
+}
+
+func orDefaultTransport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		return http.DefaultTransport
+	}
+
+	return transport
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
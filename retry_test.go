@@ -0,0 +1,195 @@
+package qst_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	newServer := func(failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Method == http.MethodPut {
+				assert.Equal(t, "Part of a complete breakfast.", string(body))
+			}
+
+			if atomic.AddInt32(&attempts, 1) <= failures {
+				for key, values := range header {
+					w.Header()[key] = values
+				}
+
+				w.WriteHeader(status)
+			}
+		}))
+
+		return server, &attempts
+	}
+
+	policy := &qst.RetryPolicy{BaseDelay: time.Millisecond}
+
+	t.Run("retries retryable status codes", func(t *testing.T) {
+		server, attempts := newServer(2, http.StatusServiceUnavailable, nil)
+		defer server.Close()
+
+		response, err := qst.Put(server.URL,
+			qst.WithRetry(policy),
+			qst.WithBodyString("Part of a complete breakfast."),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.EqualValues(t, 3, *attempts)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		server, attempts := newServer(5, http.StatusBadGateway, nil)
+		defer server.Close()
+
+		response, err := qst.Get(server.URL, qst.WithRetry(policy))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+		assert.EqualValues(t, 3, *attempts)
+	})
+
+	t.Run("doesn't retry other status codes", func(t *testing.T) {
+		server, attempts := newServer(1, http.StatusInternalServerError, nil)
+		defer server.Close()
+
+		response, err := qst.Get(server.URL, qst.WithRetry(policy))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+		assert.EqualValues(t, 1, *attempts)
+	})
+
+	t.Run("doesn't retry POST without an idempotency key", func(t *testing.T) {
+		server, attempts := newServer(1, http.StatusServiceUnavailable, nil)
+		defer server.Close()
+
+		response, err := qst.Post(server.URL, qst.WithRetry(policy))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+		assert.EqualValues(t, 1, *attempts)
+	})
+
+	t.Run("retries POST with an idempotency key", func(t *testing.T) {
+		server, attempts := newServer(1, http.StatusServiceUnavailable, nil)
+		defer server.Close()
+
+		response, err := qst.Post(server.URL,
+			qst.WithRetry(policy),
+			qst.WithHeader("Idempotency-Key", "1234"),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.EqualValues(t, 2, *attempts)
+	})
+
+	t.Run("doesn't retry unrewindable bodies", func(t *testing.T) {
+		server, attempts := newServer(1, http.StatusServiceUnavailable, nil)
+		defer server.Close()
+
+		response, err := qst.Put(server.URL,
+			qst.WithRetry(policy),
+			qst.WithBodyReader(struct{ io.Reader }{strings.NewReader("Part of a complete breakfast.")}),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+		assert.EqualValues(t, 1, *attempts)
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		server, _ := newServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+		defer server.Close()
+
+		var delays []time.Duration
+		start := time.Now()
+		response, err := qst.Get(server.URL, qst.WithRetry(&qst.RetryPolicy{
+			OnAttempt: func(attempt qst.RetryAttempt) { delays = append(delays, attempt.Delay) },
+		}))
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, []time.Duration{time.Second, 0}, delays)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("doesn't wait for a Retry-After over MaxDelay", func(t *testing.T) {
+		server, attempts := newServer(1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"86400"}})
+		defer server.Close()
+
+		start := time.Now()
+		response, err := qst.Get(server.URL, qst.WithRetry(policy))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+		assert.Equal(t, "86400", response.Header.Get("Retry-After"))
+		assert.EqualValues(t, 1, *attempts)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("respects context", func(t *testing.T) {
+		server, attempts := newServer(5, http.StatusServiceUnavailable, nil)
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := qst.Get(server.URL,
+			qst.WithContext(ctx),
+			qst.WithRetry(&qst.RetryPolicy{BaseDelay: time.Minute}),
+		)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualValues(t, 1, *attempts)
+	})
+
+	t.Run("retries network errors", func(t *testing.T) {
+		server, _ := newServer(0, 0, nil)
+		server.Close()
+
+		var attempts []qst.RetryAttempt
+		_, err := qst.Get(server.URL, qst.WithRetry(&qst.RetryPolicy{
+			BaseDelay: time.Millisecond,
+			OnAttempt: func(attempt qst.RetryAttempt) { attempts = append(attempts, attempt) },
+		}))
+
+		assert.Error(t, err)
+		require.Len(t, attempts, 3)
+		for i, attempt := range attempts {
+			assert.Equal(t, i+1, attempt.Attempt)
+			assert.Error(t, attempt.Err)
+			assert.Equal(t, i < 2, attempt.Retry)
+		}
+	})
+
+	t.Run("client policy", func(t *testing.T) {
+		server, attempts := newServer(1, http.StatusGatewayTimeout, nil)
+		defer server.Close()
+
+		client := qst.NewClient(nil, server.URL)
+		client.Retry = policy
+
+		response, err := client.Get("/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.EqualValues(t, 2, *attempts)
+
+		response, err = client.Get("/", qst.WithRetry(nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.EqualValues(t, 3, *attempts)
+	})
+}
@@ -0,0 +1,445 @@
+package qst
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	pkgurl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+// ErrMissingComponent is returned when a covered component of a message signature isn't present in the request.
+var ErrMissingComponent = errors.New("missing covered component")
+
+// SignatureAlgorithm signs the signature base of an HTTP message signature.
+type SignatureAlgorithm interface {
+	// Name is the algorithm's name in the HTTP Signature Algorithms registry, e.g. "hmac-sha256".
+	Name() string
+
+	// Sign returns the signature of base.
+	Sign(base []byte) ([]byte, error)
+}
+
+// HMACSHA256 returns a SignatureAlgorithm which signs with HMAC using SHA-256 and key.
+func HMACSHA256(key []byte) SignatureAlgorithm {
+	return hmacSHA256Algorithm(key)
+}
+
+type hmacSHA256Algorithm []byte
+
+func (a hmacSHA256Algorithm) Name() string { return "hmac-sha256" }
+
+func (a hmacSHA256Algorithm) Sign(base []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, a)
+	mac.Write(base)
+	return mac.Sum(nil), nil
+}
+
+// Ed25519 returns a SignatureAlgorithm which signs with EdDSA using curve Ed25519 and key.
+func Ed25519(key ed25519.PrivateKey) SignatureAlgorithm {
+	return ed25519Algorithm(key)
+}
+
+type ed25519Algorithm ed25519.PrivateKey
+
+func (a ed25519Algorithm) Name() string { return "ed25519" }
+
+func (a ed25519Algorithm) Sign(base []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(a), base), nil
+}
+
+// RSAPSSSHA512 returns a SignatureAlgorithm which signs with RSASSA-PSS using SHA-512 and key.
+func RSAPSSSHA512(key *rsa.PrivateKey) SignatureAlgorithm {
+	return rsaPSSSHA512Algorithm{key: key}
+}
+
+type rsaPSSSHA512Algorithm struct {
+	key *rsa.PrivateKey
+}
+
+func (a rsaPSSSHA512Algorithm) Name() string { return "rsa-pss-sha512" }
+
+func (a rsaPSSSHA512Algorithm) Sign(base []byte) ([]byte, error) {
+	digest := sha512.Sum512(base)
+	return rsa.SignPSS(rand.Reader, a.key, crypto.SHA512, digest[:], &rsa.PSSOptions{SaltLength: 64})
+}
+
+// MessageSigner signs requests with HTTP Message Signatures (RFC 9421).
+type MessageSigner struct {
+	// Algorithm signs the signature base.
+	Algorithm SignatureAlgorithm
+
+	// KeyID, if set, is the "keyid" parameter of the signature.
+	KeyID string
+
+	// Label is the label of the signature in the "Signature-Input" and "Signature" headers. If empty, "sig1" is used.
+	Label string
+
+	// Components are the covered components, e.g. "@method", "@authority", `@query-param;name="page"`, or "content-digest".
+	// Header fields are lowercase. If "content-digest" is covered and the request has no "Content-Digest" header, one is
+	// computed with sha-512.
+	Components []string
+
+	// IncludeAlgorithm sets the "alg" parameter of the signature.
+	IncludeAlgorithm bool
+
+	// Expires, if set, sets the "expires" parameter of the signature to the creation time plus Expires.
+	Expires time.Duration
+
+	// Nonce, if set, returns the "nonce" parameter of each signature.
+	Nonce func() string
+
+	// Tag, if set, is the "tag" parameter of the signature.
+	Tag string
+
+	// Now returns the creation time of signatures. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+type messageSignersKey struct{}
+
+// WithMessageSignature signs the *http.Request with signer when it is sent by a *Client.
+// Signing happens after any middleware, retries and WithContentDigest, so the signature covers the request as sent.
+// Signers from multiple calls accumulate, each adding its own signature, and override any Signer set on the *Client.
+func WithMessageSignature(signer *MessageSigner) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		existing, _ := request.Context().Value(messageSignersKey{}).([]*MessageSigner)
+		combined := make([]*MessageSigner, 0, len(existing)+1)
+		combined = append(combined, existing...)
+		combined = append(combined, signer)
+
+		return WithContextValue(messageSignersKey{}, combined).Apply(request)
+	})
+}
+
+// signingMiddleware returns a Middleware which sets the Content-Digest configured with WithContentDigest and then
+// signs the request, with the signers from WithMessageSignature or else clientSigner. It returns nil if there's
+// nothing to do.
+func signingMiddleware(request *http.Request, clientSigner *MessageSigner) Middleware {
+	algorithms, _ := request.Context().Value(contentDigestKey{}).([]string)
+	signers, _ := request.Context().Value(messageSignersKey{}).([]*MessageSigner)
+	if len(signers) == 0 && clientSigner != nil {
+		signers = []*MessageSigner{clientSigner}
+	}
+
+	if len(algorithms) == 0 && len(signers) == 0 {
+		return nil
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		next = orDefaultTransport(next)
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			signed := request.Clone(request.Context())
+			if len(algorithms) > 0 {
+				if err := setContentDigest(signed, algorithms); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, signer := range signers {
+				if err := signer.Sign(signed); err != nil {
+					return nil, err
+				}
+			}
+
+			return next.RoundTrip(signed)
+		})
+	}
+}
+
+// RoundTripper wraps next, signing each request before it is sent.
+// Middleware inside it can still change the request after it is signed; Client.Signer and WithMessageSignature
+// sign after all middleware instead.
+func (s *MessageSigner) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		signed := request.Clone(request.Context())
+		if err := s.Sign(signed); err != nil {
+			return nil, err
+		}
+
+		return next.RoundTrip(signed)
+	})
+}
+
+// Sign adds the signature of request to its "Signature-Input" and "Signature" headers.
+func (s *MessageSigner) Sign(request *http.Request) error {
+	for _, component := range s.Components {
+		if component == "content-digest" && request.Header.Get("Content-Digest") == "" {
+			if err := setContentDigest(request, []string{"sha-512"}); err != nil {
+				return err
+			}
+		}
+	}
+
+	params, err := s.signatureParams()
+	if err != nil {
+		return err
+	}
+
+	base, err := SignatureBase(request, params)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.Algorithm.Sign([]byte(base))
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+
+	label := s.Label
+	if label == "" {
+		label = "sig1"
+	}
+
+	request.Header.Add("Signature-Input", fmt.Sprintf("%s=%s", label, params))
+	request.Header.Add("Signature", fmt.Sprintf("%s=:%s:", label, base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// signatureParams returns the serialized "@signature-params" of a new signature.
+func (s *MessageSigner) signatureParams() (string, error) {
+	identifiers := make([]string, len(s.Components))
+	for i, component := range s.Components {
+		identifier, err := componentIdentifier(component)
+		if err != nil {
+			return "", err
+		}
+
+		identifiers[i] = identifier
+	}
+
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+
+	params := fmt.Sprintf("(%s);created=%d", strings.Join(identifiers, " "), now.Unix())
+	if s.Expires > 0 {
+		params += fmt.Sprintf(";expires=%d", now.Add(s.Expires).Unix())
+	}
+
+	if s.Nonce != nil {
+		params += fmt.Sprintf(";nonce=%s", strconv.Quote(s.Nonce()))
+	}
+
+	if s.IncludeAlgorithm {
+		params += fmt.Sprintf(";alg=%s", strconv.Quote(s.Algorithm.Name()))
+	}
+
+	if s.KeyID != "" {
+		params += fmt.Sprintf(";keyid=%s", strconv.Quote(s.KeyID))
+	}
+
+	if s.Tag != "" {
+		params += fmt.Sprintf(";tag=%s", strconv.Quote(s.Tag))
+	}
+
+	return params, nil
+}
+
+// SignatureBase returns the signature base (RFC 9421 section 2.5) of request for the serialized signature parameters
+// params, e.g. `("@method" "@authority");created=1618884473;keyid="test-key"`.
+func SignatureBase(request *http.Request, params string) (string, error) {
+	end := strings.Index(params, ")")
+	if !strings.HasPrefix(params, "(") || end < 0 {
+		return "", fmt.Errorf("invalid signature parameters %q", params)
+	}
+
+	var base strings.Builder
+	for _, identifier := range splitInnerList(params[1:end]) {
+		name, componentParams, err := parseComponentIdentifier(identifier)
+		if err != nil {
+			return "", err
+		}
+
+		value, err := componentValue(request, name, componentParams)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&base, "%s: %s\n", identifier, value)
+	}
+
+	fmt.Fprintf(&base, "\"@signature-params\": %s", params)
+	return base.String(), nil
+}
+
+// componentIdentifier serializes component, e.g. `@query-param;name="page"` becomes `"@query-param";name="page"`.
+func componentIdentifier(component string) (string, error) {
+	name, params := component, ""
+	if i := strings.Index(component, ";"); i >= 0 {
+		name, params = component[:i], component[i:]
+	}
+
+	if name == "" || name != strings.ToLower(name) || strings.ContainsAny(name, "\" ") {
+		return "", fmt.Errorf("invalid component %q", component)
+	}
+
+	return strconv.Quote(name) + params, nil
+}
+
+// parseComponentIdentifier returns the name and parameters of a serialized component identifier.
+func parseComponentIdentifier(identifier string) (string, map[string]string, error) {
+	end := strings.Index(identifier[1:], "\"") + 1
+	if !strings.HasPrefix(identifier, "\"") || end < 1 {
+		return "", nil, fmt.Errorf("invalid component identifier %q", identifier)
+	}
+
+	params := make(map[string]string)
+	for _, param := range strings.Split(identifier[end+1:], ";") {
+		if param == "" {
+			continue
+		}
+
+		key, value := param, ""
+		if i := strings.Index(param, "="); i >= 0 {
+			key, value = param[:i], param[i+1:]
+		}
+
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		params[key] = value
+	}
+
+	return identifier[1:end], params, nil
+}
+
+// splitInnerList splits the space separated items of a structured field inner list, respecting quotes.
+func splitInnerList(list string) []string {
+	var (
+		items  []string
+		quoted bool
+		start  int
+	)
+
+	for i := 0; i < len(list); i++ {
+		switch {
+		case list[i] == '\\' && quoted:
+			i++
+		case list[i] == '"':
+			quoted = !quoted
+		case list[i] == ' ' && !quoted:
+			if i > start {
+				items = append(items, list[start:i])
+			}
+
+			start = i + 1
+		}
+	}
+
+	if start < len(list) {
+		items = append(items, list[start:])
+	}
+
+	return items
+}
+
+// componentValue returns the value of the component name of request.
+func componentValue(request *http.Request, name string, params map[string]string) (string, error) {
+	url := request.URL
+	switch name {
+	case "@method":
+		return request.Method, nil
+
+	case "@target-uri":
+		target := *url
+		target.Host = messageAuthority(request)
+		return target.String(), nil
+
+	case "@authority":
+		return messageAuthority(request), nil
+
+	case "@scheme":
+		return strings.ToLower(url.Scheme), nil
+
+	case "@request-target":
+		return url.RequestURI(), nil
+
+	case "@path":
+		if path := url.EscapedPath(); path != "" {
+			return path, nil
+		}
+
+		return "/", nil
+
+	case "@query":
+		return "?" + url.RawQuery, nil
+
+	case "@query-param":
+		for _, pair := range strings.Split(url.RawQuery, "&") {
+			key, value := pair, ""
+			if i := strings.Index(pair, "="); i >= 0 {
+				key, value = pair[:i], pair[i+1:]
+			}
+
+			if key, err := pkgurl.QueryUnescape(key); err != nil || key != params["name"] {
+				continue
+			}
+
+			value, err := pkgurl.QueryUnescape(value)
+			if err != nil {
+				return "", err
+			}
+
+			return strings.Replace(pkgurl.QueryEscape(value), "+", "%20", -1), nil
+		}
+
+		return "", fmt.Errorf("%w: query parameter %q", ErrMissingComponent, params["name"])
+	}
+
+	if strings.HasPrefix(name, "@") {
+		return "", fmt.Errorf("unsupported derived component %q", name)
+	}
+
+	values := request.Header.Values(name)
+	if len(values) == 0 {
+		switch {
+		case name == "host":
+			return messageAuthority(request), nil
+		case name == "content-length" && request.ContentLength > 0:
+			return strconv.FormatInt(request.ContentLength, 10), nil
+		}
+
+		return "", fmt.Errorf("%w: header %q", ErrMissingComponent, name)
+	}
+
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+
+	return strings.Join(trimmed, ", "), nil
+}
+
+// messageAuthority returns the lowercase host of request, without a default port.
+func messageAuthority(request *http.Request) string {
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+
+	host = strings.ToLower(host)
+	switch {
+	case strings.HasSuffix(host, ":80") && request.URL.Scheme == "http":
+		return strings.TrimSuffix(host, ":80")
+	case strings.HasSuffix(host, ":443") && request.URL.Scheme == "https":
+		return strings.TrimSuffix(host, ":443")
+	}
+
+	return host
+}
@@ -0,0 +1,331 @@
+package qst
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	pkgurl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+const (
+	awsSigV4Algorithm  = "AWS4-HMAC-SHA256"
+	awsDateFormat      = "20060102"
+	awsTimeFormat      = "20060102T150405Z"
+	awsUnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// awsUnsignedHeaders are headers which are never signed, since they are commonly changed in transit.
+var awsUnsignedHeaders = map[string]bool{
+	"Authorization":   true,
+	"User-Agent":      true,
+	"X-Amzn-Trace-Id": true,
+	"Expect":          true,
+}
+
+// AWSCredentials are the credentials used to sign requests with AWS Signature Version 4.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken, if set, is sent in the "X-Amz-Security-Token" header or query parameter.
+	SessionToken string
+}
+
+// AWSSigner signs requests with AWS Signature Version 4.
+type AWSSigner struct {
+	Credentials AWSCredentials
+	Region      string
+	Service     string
+
+	// Now returns the signing time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// WithAWSSigV4 signs the *http.Request with AWS Signature Version 4 when it is sent, after any other options have been applied.
+func WithAWSSigV4(credentials AWSCredentials, region, service string) option.Option[*http.Request] {
+	signer := &AWSSigner{Credentials: credentials, Region: region, Service: service}
+	return WithMiddleware(signer.RoundTripper)
+}
+
+// RoundTripper wraps next, signing each request before it is sent.
+func (s *AWSSigner) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	next = orDefaultTransport(next)
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		signed := request.Clone(request.Context())
+		if err := s.Sign(signed); err != nil {
+			return nil, err
+		}
+
+		return next.RoundTrip(signed)
+	})
+}
+
+// Sign signs request, setting its "X-Amz-Date" and "Authorization" headers.
+// The payload hash is taken from the "X-Amz-Content-Sha256" header if set, and otherwise computed from the body.
+func (s *AWSSigner) Sign(request *http.Request) error {
+	now := s.now()
+	request.Header.Set("X-Amz-Date", now.Format(awsTimeFormat))
+	if s.Credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+
+	payloadHash := request.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		body, err := peekBody(request)
+		if err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
+
+		payloadHash = hashHex(body)
+		if s.Service == "s3" {
+			request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+		}
+	}
+
+	signedHeaders, canonicalHeaders := s.canonicalHeaders(request)
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		s.canonicalURI(request.URL),
+		canonicalQuery(request.URL.RawQuery),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigV4Algorithm,
+		s.Credentials.AccessKeyID,
+		s.scope(now),
+		signedHeaders,
+		s.signature(now, canonicalRequest),
+	))
+
+	return nil
+}
+
+// Presign returns a copy of the URL of request with a signature in its query, valid for expires.
+// For S3, the payload is left unsigned, as it is for presigned URLs generated by AWS.
+func (s *AWSSigner) Presign(request *http.Request, expires time.Duration) (*pkgurl.URL, error) {
+	now := s.now()
+	presigned := *request.URL
+
+	query := presigned.Query()
+	query.Set("X-Amz-Algorithm", awsSigV4Algorithm)
+	query.Set("X-Amz-Credential", fmt.Sprintf("%s/%s", s.Credentials.AccessKeyID, s.scope(now)))
+	query.Set("X-Amz-Date", now.Format(awsTimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	if s.Credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := s.canonicalHeaders(request)
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	presigned.RawQuery = strings.Replace(query.Encode(), "+", "%20", -1)
+
+	payloadHash := awsUnsignedPayload
+	if s.Service != "s3" {
+		body, err := peekBody(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body: %w", err)
+		}
+
+		payloadHash = hashHex(body)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		s.canonicalURI(&presigned),
+		canonicalQuery(presigned.RawQuery),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	presigned.RawQuery += "&X-Amz-Signature=" + s.signature(now, canonicalRequest)
+	return &presigned, nil
+}
+
+func (s *AWSSigner) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+
+	return time.Now().UTC()
+}
+
+func (s *AWSSigner) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/%s/aws4_request", now.Format(awsDateFormat), s.Region, s.Service)
+}
+
+func (s *AWSSigner) signature(now time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		awsSigV4Algorithm,
+		now.Format(awsTimeFormat),
+		s.scope(now),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.Credentials.SecretAccessKey), now.Format(awsDateFormat))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalURI returns the URI-encoded path of url. Other than for S3, the path is normalized and encoded twice.
+// The path is encoded from its escaped form segment by segment, so an escaped "/" within a segment stays escaped.
+func (s *AWSSigner) canonicalURI(url *pkgurl.URL) string {
+	path := url.EscapedPath()
+	if url.Opaque != "" {
+		path = url.Opaque
+	}
+
+	if s.Service == "s3" {
+		if path == "" {
+			return "/"
+		}
+	} else {
+		path = normalizeAWSPath(path)
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if unescaped, err := pkgurl.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+
+		segments[i] = awsURIEncode(segment, true)
+		if s.Service != "s3" {
+			segments[i] = awsURIEncode(segments[i], true)
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// canonicalHeaders returns the signed header names of request, and its canonical headers.
+func (s *AWSSigner) canonicalHeaders(request *http.Request) (string, string) {
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+
+	headers := map[string]string{"host": strings.TrimSpace(host)}
+	for key, values := range request.Header {
+		if awsUnsignedHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+
+		headers[strings.ToLower(key)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name)
+		canonical.WriteString(":")
+		canonical.WriteString(headers[name])
+		canonical.WriteString("\n")
+	}
+
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// canonicalQuery returns the query parameters of rawQuery, URI-encoded and sorted.
+func canonicalQuery(rawQuery string) string {
+	var params []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		key, value := pair, ""
+		if i := strings.Index(pair, "="); i >= 0 {
+			key, value = pair[:i], pair[i+1:]
+		}
+
+		if unescaped, err := pkgurl.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		if unescaped, err := pkgurl.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+
+		params = append(params, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+	}
+
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// normalizeAWSPath removes empty, "." and ".." segments from path, keeping any trailing slash.
+func normalizeAWSPath(path string) string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	normalized := "/" + strings.Join(segments, "/")
+	if len(segments) > 0 && strings.HasSuffix(path, "/") {
+		normalized += "/"
+	}
+
+	return normalized
+}
+
+// awsURIEncode percent-encodes every byte of s other than unreserved characters, and "/" unless encodeSlash is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+
+	return encoded.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
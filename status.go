@@ -0,0 +1,170 @@
+package qst
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/broothie/option"
+)
+
+// MaxStatusErrorBodySize is the maximum number of response body bytes captured by a *StatusError.
+var MaxStatusErrorBodySize int64 = 4 << 10
+
+type expectStatusKey struct{}
+
+// WithExpectStatus causes the *http.Request to fail with a *StatusError when the response status code is not one of codes.
+func WithExpectStatus(codes ...int) option.Option[*http.Request] {
+	return WithContextValue(expectStatusKey{}, func(statusCode int) bool {
+		for _, code := range codes {
+			if statusCode == code {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// WithExpectSuccess causes the *http.Request to fail with a *StatusError when the response status code is not 2xx.
+func WithExpectSuccess() option.Option[*http.Request] {
+	return WithContextValue(expectStatusKey{}, func(statusCode int) bool {
+		return statusCode >= 200 && statusCode < 300
+	})
+}
+
+// StatusError is returned when a response status code is not expected.
+type StatusError struct {
+	// Method is the method of the request.
+	Method string
+
+	// URL is the URL of the request, with any password and the query values named by DefaultRedaction redacted.
+	URL string
+
+	// StatusCode is the status code of the response.
+	StatusCode int
+
+	// Status is the status of the response, e.g. "404 Not Found".
+	Status string
+
+	// Header is the header of the response.
+	Header http.Header
+
+	// Body is the start of the response body, up to MaxStatusErrorBodySize bytes.
+	Body []byte
+
+	// Problem is the parsed response body, if it is an "application/problem+json" document.
+	Problem *Problem
+
+	// Err is the error reading the response body, if there was one. Body holds whatever was read before it.
+	Err error
+}
+
+// Error returns the method, URL, and status of the response, along with any problem details.
+func (e *StatusError) Error() string {
+	message := fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+	if e.Problem != nil {
+		if e.Problem.Title != "" {
+			message = fmt.Sprintf("%s: %s", message, e.Problem.Title)
+		}
+
+		if e.Problem.Detail != "" {
+			message = fmt.Sprintf("%s: %s", message, e.Problem.Detail)
+		}
+	}
+
+	if e.Err != nil {
+		message = fmt.Sprintf("%s: failed to read body: %v", message, e.Err)
+	}
+
+	return message
+}
+
+// Unwrap returns the error reading the response body, if there was one.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Problem is an RFC 9457 problem details document.
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+
+	// Extensions holds any members of the document other than the standard ones.
+	Extensions map[string]interface{}
+}
+
+// UnmarshalJSON decodes a problem details document, collecting non-standard members into Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		"type":     &p.Type,
+		"title":    &p.Title,
+		"status":   &p.Status,
+		"detail":   &p.Detail,
+		"instance": &p.Instance,
+	}
+
+	for name, raw := range members {
+		if field, ok := fields[name]; ok {
+			// Members with the wrong type are ignored, per RFC 9457 section 3.1.
+			_ = json.Unmarshal(raw, field)
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]interface{})
+		}
+
+		p.Extensions[name] = value
+	}
+
+	return nil
+}
+
+func checkStatus(request *http.Request, response *http.Response) (*http.Response, error) {
+	expect, ok := request.Context().Value(expectStatusKey{}).(func(int) bool)
+	if !ok || expect(response.StatusCode) {
+		return response, nil
+	}
+
+	defer response.Body.Close()
+
+	statusErr := &StatusError{
+		Method:     request.Method,
+		URL:        DefaultRedaction.redactURL(request.URL).Redacted(),
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Header:     response.Header,
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, MaxStatusErrorBodySize))
+	statusErr.Body = body
+	if err != nil {
+		statusErr.Err = err
+		return response, statusErr
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type")); err == nil && mediaType == "application/problem+json" {
+		problem := new(Problem)
+		if err := json.Unmarshal(body, problem); err == nil {
+			statusErr.Problem = problem
+		}
+	}
+
+	return response, statusErr
+}
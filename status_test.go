@@ -0,0 +1,101 @@
+package qst_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"type":"https://breakfast.com/probs/out-of-milk","title":"Out of milk","status":403,"detail":"The carton is empty.","balance":30}`)
+
+		case "/truncated":
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "partial")
+
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, strings.Repeat("x", 10000))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("expected", func(t *testing.T) {
+		response, err := qst.Post(server.URL+"/created", qst.WithExpectStatus(http.StatusOK, http.StatusCreated))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+	})
+
+	t.Run("not expected", func(t *testing.T) {
+		response, err := qst.Get(server.URL+"/created", qst.WithExpectStatus(http.StatusOK))
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+
+		var statusErr *qst.StatusError
+		require.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusCreated, statusErr.StatusCode)
+		assert.Equal(t, http.MethodGet, statusErr.Method)
+	})
+
+	t.Run("no expectation", func(t *testing.T) {
+		response, err := qst.Get(server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	})
+
+	t.Run("body is bounded and URL is redacted", func(t *testing.T) {
+		url := strings.Replace(server.URL, "http://", "http://TonyTheTiger:grrreat@", 1)
+		_, err := qst.Get(url, qst.WithExpectSuccess(), qst.WithQuery("api_key", "c0rnfl@k3s"), qst.WithQuery("page", "2"))
+
+		var statusErr *qst.StatusError
+		require.True(t, errors.As(err, &statusErr))
+		assert.Len(t, statusErr.Body, int(qst.MaxStatusErrorBodySize))
+		assert.NotContains(t, statusErr.URL, "grrreat")
+		assert.NotContains(t, statusErr.URL, "c0rnfl@k3s")
+		assert.Contains(t, statusErr.URL, "page=2")
+		assert.Nil(t, statusErr.Problem)
+		assert.EqualError(t, err, fmt.Sprintf("GET %s: 500 Internal Server Error", statusErr.URL))
+	})
+
+	t.Run("body read error", func(t *testing.T) {
+		_, err := qst.Get(server.URL+"/truncated", qst.WithExpectSuccess())
+
+		var statusErr *qst.StatusError
+		require.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+		assert.Equal(t, "partial", string(statusErr.Body))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("problem details", func(t *testing.T) {
+		_, err := qst.Get(server.URL+"/problem", qst.WithExpectSuccess())
+
+		var statusErr *qst.StatusError
+		require.True(t, errors.As(err, &statusErr))
+		require.NotNil(t, statusErr.Problem)
+		assert.Equal(t, &qst.Problem{
+			Type:       "https://breakfast.com/probs/out-of-milk",
+			Title:      "Out of milk",
+			Status:     http.StatusForbidden,
+			Detail:     "The carton is empty.",
+			Extensions: map[string]interface{}{"balance": float64(30)},
+		}, statusErr.Problem)
+		assert.EqualError(t, err, fmt.Sprintf("GET %s/problem: 403 Forbidden: Out of milk: The carton is empty.", server.URL))
+	})
+}
@@ -0,0 +1,404 @@
+package qst
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	pkgurl "net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/broothie/option"
+)
+
+// Nesting is how the keys of nested structs and maps are joined.
+type Nesting int
+
+const (
+	// NestingDot joins nested keys with dots, e.g. "user.address.city".
+	NestingDot Nesting = iota
+
+	// NestingBrackets joins nested keys with brackets, e.g. "user[address][city]".
+	NestingBrackets
+
+	// NestingRails joins nested keys with brackets and adds "[]" to the keys of slice elements, as Rails and PHP expect,
+	// e.g. "user[address][city]=Battle+Creek&user[tags][]=a&user[tags][]=b".
+	NestingRails
+)
+
+// StructEncoder encodes structs into url.Values, configured by "qst" struct tags, or "url" tags if there is no "qst" tag.
+//
+// A tag is a name followed by comma-separated options, e.g. `qst:"created_at,omitempty,unix"`. A name of "-" skips the field.
+// The options are:
+//   - omitempty: skip the field if it is a zero value or an empty slice or map.
+//   - comma, space, semicolon: join slice elements with the separator into a single value. By default, each element is added separately.
+//   - brackets: add "[]" to the key of each slice element, e.g. "tags[]=a&tags[]=b". This is the default with NestingRails.
+//   - int: encode bools as "1" or "0".
+//   - unix, unixmilli: encode a time.Time as a Unix timestamp in seconds or milliseconds.
+//
+// A time.Time is formatted with the layout in a `layout:"..."` tag, or time.RFC3339 if there is none.
+// Values implementing encoding.TextMarshaler are encoded with MarshalText.
+// Nested structs, maps, and slices of them are encoded with keys joined according to Nesting.
+// Embedded structs without a tag name have their fields promoted.
+type StructEncoder struct {
+	// Nesting is how nested keys are joined. Defaults to NestingDot.
+	Nesting Nesting
+}
+
+// WithQueryStruct encodes v with a StructEncoder and applies the result to the query parameters of the *http.Request.
+func WithQueryStruct(v interface{}) option.Option[*http.Request] {
+	return StructEncoder{}.Query(v)
+}
+
+// WithBodyFormStruct encodes v with a StructEncoder and applies the result to the *http.Request body as a URL-encoded form.
+func WithBodyFormStruct(v interface{}) option.Option[*http.Request] {
+	return StructEncoder{}.BodyForm(v)
+}
+
+// BodyForm encodes v and applies the result to the *http.Request body as a URL-encoded form.
+func (e StructEncoder) BodyForm(v interface{}) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		values, err := e.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return WithBodyForm(values).Apply(request)
+	})
+}
+
+// Query encodes v and applies the result to the query parameters of the *http.Request.
+func (e StructEncoder) Query(v interface{}) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		values, err := e.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return WithQueries(values).Apply(request)
+	})
+}
+
+// Encode encodes v, which must be a struct, a map with string keys, or a pointer to one, into url.Values.
+func (e StructEncoder) Encode(v interface{}) (pkgurl.Values, error) {
+	values := make(pkgurl.Values)
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return values, nil
+		}
+
+		value = value.Elem()
+	}
+
+	var err error
+	switch value.Kind() {
+	case reflect.Struct:
+		err = e.encodeStruct(values, "", value)
+
+	case reflect.Map:
+		err = e.encodeMap(values, "", value)
+
+	default:
+		err = fmt.Errorf("cannot encode %T: must be a struct or map", v)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+type structTag struct {
+	name      string
+	omitEmpty bool
+	separator string
+	brackets  bool
+	intBool   bool
+	unix      bool
+	unixMilli bool
+	layout    string
+}
+
+func parseStructTag(field reflect.StructField) (structTag, bool) {
+	tagValue, ok := field.Tag.Lookup("qst")
+	if !ok {
+		tagValue = field.Tag.Get("url")
+	}
+
+	if tagValue == "-" {
+		return structTag{}, false
+	}
+
+	name, rest, _ := strings.Cut(tagValue, ",")
+	tag := structTag{name: name, layout: field.Tag.Get("layout")}
+	for _, tagOption := range strings.Split(rest, ",") {
+		switch tagOption {
+		case "omitempty":
+			tag.omitEmpty = true
+		case "comma":
+			tag.separator = ","
+		case "space":
+			tag.separator = " "
+		case "semicolon":
+			tag.separator = ";"
+		case "brackets":
+			tag.brackets = true
+		case "int":
+			tag.intBool = true
+		case "unix":
+			tag.unix = true
+		case "unixmilli":
+			tag.unixMilli = true
+		}
+	}
+
+	return tag, true
+}
+
+func (e StructEncoder) encodeStruct(values pkgurl.Values, prefix string, value reflect.Value) error {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, ok := parseStructTag(field)
+		if !ok {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if tag.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		if field.Anonymous && tag.name == "" {
+			embedded := indirect(fieldValue)
+			if embedded.Kind() == reflect.Struct && !isScalar(embedded) {
+				if err := e.encodeStruct(values, prefix, embedded); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name := tag.name
+		if name == "" {
+			name = field.Name
+		}
+
+		if err := e.encodeValue(values, e.key(prefix, name), fieldValue, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e StructEncoder) encodeMap(values pkgurl.Values, prefix string, value reflect.Value) error {
+	if value.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cannot encode %s: map keys must be strings", value.Type())
+	}
+
+	keys := value.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, key := range keys {
+		if err := e.encodeValue(values, e.key(prefix, key.String()), value.MapIndex(key), structTag{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e StructEncoder) encodeValue(values pkgurl.Values, key string, value reflect.Value, tag structTag) error {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			values.Add(key, "")
+			return nil
+		}
+
+		value = value.Elem()
+	}
+
+	if s, ok, err := encodeScalar(value, tag); err != nil {
+		return err
+	} else if ok {
+		values.Add(key, s)
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		return e.encodeSlice(values, key, value, tag)
+
+	case reflect.Struct:
+		return e.encodeStruct(values, key, value)
+
+	case reflect.Map:
+		return e.encodeMap(values, key, value)
+
+	default:
+		return fmt.Errorf("cannot encode %s at %q", value.Type(), key)
+	}
+}
+
+func (e StructEncoder) encodeSlice(values pkgurl.Values, key string, value reflect.Value, tag structTag) error {
+	var elements []string
+	for i := 0; i < value.Len(); i++ {
+		element := indirect(value.Index(i))
+		if element.Kind() == reflect.Pointer || element.Kind() == reflect.Interface {
+			elements = append(elements, "")
+			continue
+		}
+
+		if (element.Kind() == reflect.Struct && !isScalar(element)) || element.Kind() == reflect.Map {
+			if err := e.encodeValue(values, e.key(key, strconv.Itoa(i)), element, structTag{}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		s, ok, err := encodeScalar(element, tag)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("cannot encode %s at %q", element.Type(), key)
+		}
+
+		elements = append(elements, s)
+	}
+
+	if len(elements) == 0 {
+		return nil
+	}
+
+	if tag.separator != "" {
+		values.Add(key, strings.Join(elements, tag.separator))
+		return nil
+	}
+
+	if tag.brackets || e.Nesting == NestingRails {
+		key = fmt.Sprintf("%s[]", key)
+	}
+
+	for _, element := range elements {
+		values.Add(key, element)
+	}
+
+	return nil
+}
+
+func (e StructEncoder) key(prefix, name string) string {
+	switch {
+	case prefix == "":
+		return name
+	case e.Nesting == NestingBrackets || e.Nesting == NestingRails:
+		return fmt.Sprintf("%s[%s]", prefix, name)
+	default:
+		return fmt.Sprintf("%s.%s", prefix, name)
+	}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isScalar reports whether value is encoded as a single string despite being a struct, as with time.Time.
+func isScalar(value reflect.Value) bool {
+	return value.Type() == timeType || value.Type().Implements(textMarshalerType)
+}
+
+// encodeScalar encodes value as a single string, and reports whether it could.
+func encodeScalar(value reflect.Value, tag structTag) (string, bool, error) {
+	if !value.IsValid() {
+		return "", true, nil
+	}
+
+	if !value.CanInterface() {
+		return "", false, nil
+	}
+
+	if value.Type() == timeType {
+		t := value.Interface().(time.Time)
+		switch {
+		case tag.unix:
+			return strconv.FormatInt(t.Unix(), 10), true, nil
+		case tag.unixMilli:
+			return strconv.FormatInt(t.UnixMilli(), 10), true, nil
+		case tag.layout != "":
+			return t.Format(tag.layout), true, nil
+		default:
+			return t.Format(time.RFC3339), true, nil
+		}
+	}
+
+	if value.Type().Implements(textMarshalerType) {
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", false, err
+		}
+
+		return string(text), true, nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), true, nil
+
+	case reflect.Bool:
+		if tag.intBool {
+			if value.Bool() {
+				return "1", true, nil
+			}
+
+			return "0", true, nil
+		}
+
+		return strconv.FormatBool(value.Bool()), true, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), true, nil
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits()), true, nil
+
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return string(value.Bytes()), true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	return value
+}
@@ -0,0 +1,196 @@
+package qst_test
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cerealFilter struct {
+	Name      string    `qst:"name,omitempty"`
+	Page      int       `qst:"page"`
+	Limit     *int      `qst:"limit,omitempty"`
+	Frosted   bool      `qst:"frosted,int"`
+	Raisins   bool      `url:"raisins"`
+	Rating    float64   `qst:"rating,omitempty"`
+	Grains    []string  `qst:"grain"`
+	Colors    []string  `qst:"colors,comma"`
+	Shapes    []string  `qst:"shapes,brackets"`
+	Since     time.Time `qst:"since" layout:"2006-01-02"`
+	Until     time.Time `qst:"until,unix"`
+	Updated   time.Time `qst:"updated,omitempty"`
+	Server    net.IP    `qst:"server"`
+	Box       box       `qst:"box"`
+	Secret    string    `qst:"-"`
+	Untagged  string
+	unexposed string
+	paging
+}
+
+type box struct {
+	Size  string `qst:"size"`
+	Color string `qst:"color,omitempty"`
+}
+
+type paging struct {
+	Cursor string `qst:"cursor,omitempty"`
+}
+
+func TestStructEncoder_Encode(t *testing.T) {
+	since := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	filter := cerealFilter{
+		Page:      2,
+		Frosted:   true,
+		Rating:    4.5,
+		Grains:    []string{"oats", "corn"},
+		Colors:    []string{"red", "blue"},
+		Shapes:    []string{"o", "x"},
+		Since:     since,
+		Until:     since,
+		Server:    net.IPv4(127, 0, 0, 1),
+		Box:       box{Size: "family"},
+		Secret:    "shh",
+		Untagged:  "yes",
+		unexposed: "no",
+		paging:    paging{Cursor: "abc"},
+	}
+
+	t.Run("dot nesting", func(t *testing.T) {
+		values, err := qst.StructEncoder{}.Encode(&filter)
+		require.NoError(t, err)
+		assert.Equal(t, "Untagged=yes&box.size=family&colors=red%2Cblue&cursor=abc&frosted=1&grain=oats&grain=corn&page=2&raisins=false&rating=4.5&server=127.0.0.1&shapes%5B%5D=o&shapes%5B%5D=x&since=2023-04-05&until=1680674828", values.Encode())
+	})
+
+	t.Run("bracket nesting", func(t *testing.T) {
+		values, err := qst.StructEncoder{Nesting: qst.NestingBrackets}.Encode(filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"family"}, values["box[size]"])
+	})
+
+	t.Run("maps and slices of structs", func(t *testing.T) {
+		values, err := qst.StructEncoder{}.Encode(map[string]interface{}{
+			"boxes": []box{{Size: "small"}, {Size: "large", Color: "red"}},
+			"tags":  map[string]int{"b": 2, "a": 1},
+			"none":  nil,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "boxes.0.size=small&boxes.1.color=red&boxes.1.size=large&none=&tags.a=1&tags.b=2", values.Encode())
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		values, err := qst.StructEncoder{}.Encode((*cerealFilter)(nil))
+		require.NoError(t, err)
+		assert.Empty(t, values)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := qst.StructEncoder{}.Encode("cereal")
+		assert.EqualError(t, err, "cannot encode string: must be a struct or map")
+
+		_, err = qst.StructEncoder{}.Encode(map[int]string{1: "cereal"})
+		assert.EqualError(t, err, "cannot encode map[int]string: map keys must be strings")
+
+		_, err = qst.StructEncoder{}.Encode(struct{ C chan int }{C: make(chan int)})
+		assert.EqualError(t, err, `cannot encode chan int at "C"`)
+	})
+}
+
+func TestWithQueryStruct(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		_, err := qst.NewGet("https://breakfast.com/api/cereals", qst.WithQueryStruct(42))
+		assert.EqualError(t, err, "failed to apply option 0: cannot encode int: must be a struct or map")
+	})
+}
+
+func ExampleWithQueryStruct() {
+	type filter struct {
+		Name   string   `qst:"name,omitempty"`
+		Page   int      `qst:"page"`
+		Grains []string `qst:"grains,comma"`
+	}
+
+	request, _ := qst.NewGet("https://breakfast.com/api/cereals",
+		qst.WithQueryStruct(filter{Page: 2, Grains: []string{"oats", "corn"}}),
+	)
+
+	fmt.Println(request.URL.Query().Encode())
+	// Output: grains=oats%2Ccorn&page=2
+}
+
+func ExampleStructEncoder_Query() {
+	type filter struct {
+		Box struct {
+			Size string `qst:"size"`
+		} `qst:"box"`
+	}
+
+	var f filter
+	f.Box.Size = "family"
+
+	request, _ := qst.NewGet("https://breakfast.com/api/cereals",
+		qst.StructEncoder{Nesting: qst.NestingBrackets}.Query(f),
+	)
+
+	fmt.Println(request.URL.RawQuery)
+	// Output: box%5Bsize%5D=family
+}
+
+func TestWithBodyFormStruct(t *testing.T) {
+	type address struct {
+		City  string `qst:"city"`
+		State string `qst:"state"`
+	}
+
+	type user struct {
+		Name     string    `qst:"name"`
+		Address  address   `qst:"address"`
+		Tags     []string  `qst:"tags"`
+		Previous []address `qst:"previous"`
+	}
+
+	type signup struct {
+		User user `qst:"user"`
+	}
+
+	form := signup{User: user{
+		Name:     "Tony",
+		Address:  address{City: "Battle Creek", State: "MI"},
+		Tags:     []string{"tiger", "mascot"},
+		Previous: []address{{City: "Omaha", State: "NE"}},
+	}}
+
+	t.Run("dot nesting", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/signup", qst.WithBodyFormStruct(form))
+		require.NoError(t, err)
+		assert.Equal(t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
+		assert.Equal(t, "user.address.city=Battle+Creek&user.address.state=MI&user.name=Tony&user.previous.0.city=Omaha&user.previous.0.state=NE&user.tags=tiger&user.tags=mascot", readAll(t, request.Body))
+	})
+
+	t.Run("rails nesting", func(t *testing.T) {
+		request, err := qst.NewPost("https://breakfast.com/api/signup", qst.StructEncoder{Nesting: qst.NestingRails}.BodyForm(form))
+		require.NoError(t, err)
+
+		values, err := url.ParseQuery(readAll(t, request.Body))
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"user[name]":               {"Tony"},
+			"user[address][city]":      {"Battle Creek"},
+			"user[address][state]":     {"MI"},
+			"user[tags][]":             {"tiger", "mascot"},
+			"user[previous][0][city]":  {"Omaha"},
+			"user[previous][0][state]": {"NE"},
+		}, values)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := qst.NewPost("https://breakfast.com/api/signup", qst.WithBodyFormStruct([]string{"Tony"}))
+		assert.EqualError(t, err, "failed to apply option 0: cannot encode []string: must be a struct or map")
+	})
+}
@@ -0,0 +1,360 @@
+package qst
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	pkgurl "net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/broothie/option"
+)
+
+// ErrInvalidURITemplate is returned when a URI template can't be parsed.
+var ErrInvalidURITemplate = errors.New("invalid URI template")
+
+// WithURITemplate expands an RFC 6570 URI template with vars and applies the result to the *http.Request URL.
+// An absolute result replaces the URL. Otherwise, its path is appended to the URL path, and its query to the URL query.
+//
+// Values may be strings, numbers, bools, or other values formatted with fmt.Sprint, slices for lists, and maps with string
+// keys for associative arrays. Associative arrays are expanded in key order. Nil values and empty lists and maps are undefined.
+func WithURITemplate(template string, vars map[string]interface{}) option.Option[*http.Request] {
+	return option.Func[*http.Request](func(request *http.Request) (*http.Request, error) {
+		expanded, err := ExpandURITemplate(template, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		reference, err := pkgurl.Parse(expanded)
+		if err != nil {
+			return nil, err
+		}
+
+		if reference.IsAbs() {
+			request.URL = reference
+			request.Host = reference.Host
+			return request, nil
+		}
+
+		if err := appendEscapedPath(request.URL, reference.EscapedPath()); err != nil {
+			return nil, err
+		}
+
+		if reference.RawQuery != "" {
+			if request.URL.RawQuery == "" {
+				request.URL.RawQuery = reference.RawQuery
+			} else {
+				request.URL.RawQuery = fmt.Sprintf("%s&%s", request.URL.RawQuery, reference.RawQuery)
+			}
+		}
+
+		if reference.Fragment != "" {
+			request.URL.Fragment = reference.Fragment
+			request.URL.RawFragment = reference.RawFragment
+		}
+
+		return request, nil
+	})
+}
+
+type uriTemplateOperator struct {
+	first, separator string
+	named            bool
+	ifEmpty          string
+	allowReserved    bool
+}
+
+var uriTemplateOperators = map[byte]uriTemplateOperator{
+	0:   {first: "", separator: ","},
+	'+': {first: "", separator: ",", allowReserved: true},
+	'#': {first: "#", separator: ",", allowReserved: true},
+	'.': {first: ".", separator: "."},
+	'/': {first: "/", separator: "/"},
+	';': {first: ";", separator: ";", named: true},
+	'?': {first: "?", separator: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", separator: "&", named: true, ifEmpty: "="},
+}
+
+// ExpandURITemplate expands an RFC 6570 URI template, up to and including level 4, with vars.
+// See WithURITemplate for how values are expanded.
+func ExpandURITemplate(template string, vars map[string]interface{}) (string, error) {
+	var result strings.Builder
+	for len(template) > 0 {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			result.WriteString(encodeURITemplateValue(template, true))
+			break
+		}
+
+		if template[start] == '}' {
+			return "", fmt.Errorf("%w: unexpected '}'", ErrInvalidURITemplate)
+		}
+
+		result.WriteString(encodeURITemplateValue(template[:start], true))
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%w: unclosed expression", ErrInvalidURITemplate)
+		}
+
+		if err := expandURITemplateExpression(&result, template[start+1:start+end], vars); err != nil {
+			return "", err
+		}
+
+		template = template[start+end+1:]
+	}
+
+	return result.String(), nil
+}
+
+func expandURITemplateExpression(result *strings.Builder, expression string, vars map[string]interface{}) error {
+	if expression == "" {
+		return fmt.Errorf("%w: empty expression", ErrInvalidURITemplate)
+	}
+
+	operator, ok := uriTemplateOperators[expression[0]]
+	if ok {
+		expression = expression[1:]
+	} else if strings.IndexByte("=,!@|", expression[0]) >= 0 {
+		return fmt.Errorf("%w: reserved operator %q", ErrInvalidURITemplate, expression[0])
+	} else {
+		operator = uriTemplateOperators[0]
+	}
+
+	first := true
+	for _, varSpec := range strings.Split(expression, ",") {
+		name, explode, prefix, err := parseURITemplateVarSpec(varSpec)
+		if err != nil {
+			return err
+		}
+
+		value, defined := uriTemplateValue(vars[name])
+		if !defined {
+			continue
+		}
+
+		if first {
+			result.WriteString(operator.first)
+			first = false
+		} else {
+			result.WriteString(operator.separator)
+		}
+
+		if err := expandURITemplateValue(result, operator, name, value, explode, prefix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseURITemplateVarSpec(varSpec string) (string, bool, int, error) {
+	name, explode, prefix := varSpec, false, 0
+	if strings.HasSuffix(name, "*") {
+		name, explode = strings.TrimSuffix(name, "*"), true
+	} else if i := strings.IndexByte(name, ':'); i >= 0 {
+		length, err := strconv.Atoi(name[i+1:])
+		if err != nil || length <= 0 || length >= 10000 || name[i+1] == '0' {
+			return "", false, 0, fmt.Errorf("%w: invalid prefix %q", ErrInvalidURITemplate, varSpec)
+		}
+
+		name, prefix = name[:i], length
+	}
+
+	if name == "" {
+		return "", false, 0, fmt.Errorf("%w: empty variable name", ErrInvalidURITemplate)
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '%' && i+2 < len(name) && isHexDigit(name[i+1]) && isHexDigit(name[i+2]):
+			i += 2
+		case c == '_' || c == '.' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+		default:
+			return "", false, 0, fmt.Errorf("%w: invalid variable name %q", ErrInvalidURITemplate, name)
+		}
+	}
+
+	return name, explode, prefix, nil
+}
+
+// uriTemplateValue normalizes a variable value into a string, []string, or [][2]string, and reports whether it is defined.
+func uriTemplateValue(v interface{}) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, false
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return string(value.Bytes()), true
+		}
+
+		list := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			if element, ok := uriTemplateValue(value.Index(i).Interface()); ok {
+				if s, ok := element.(string); ok {
+					list = append(list, s)
+				}
+			}
+		}
+
+		return list, len(list) > 0
+
+	case reflect.Map:
+		keys := value.MapKeys()
+		pairs := make([][2]string, 0, len(keys))
+		for _, key := range keys {
+			if element, ok := uriTemplateValue(value.MapIndex(key).Interface()); ok {
+				if s, ok := element.(string); ok {
+					pairs = append(pairs, [2]string{fmt.Sprint(key.Interface()), s})
+				}
+			}
+		}
+
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+		return pairs, len(pairs) > 0
+
+	default:
+		return fmt.Sprint(value.Interface()), true
+	}
+}
+
+func expandURITemplateValue(result *strings.Builder, operator uriTemplateOperator, name string, value interface{}, explode bool, prefix int) error {
+	encode := func(s string) string { return encodeURITemplateValue(s, operator.allowReserved) }
+	writeNamed := func(name, value string) {
+		result.WriteString(encode(name))
+		if value == "" {
+			result.WriteString(operator.ifEmpty)
+			return
+		}
+
+		result.WriteString("=")
+		result.WriteString(encode(value))
+	}
+
+	switch value := value.(type) {
+	case string:
+		if prefix > 0 && utf8.RuneCountInString(value) > prefix {
+			value = string([]rune(value)[:prefix])
+		}
+
+		if operator.named {
+			writeNamed(name, value)
+		} else {
+			result.WriteString(encode(value))
+		}
+
+	case []string:
+		if prefix > 0 {
+			return fmt.Errorf("%w: prefix applied to list %q", ErrInvalidURITemplate, name)
+		}
+
+		encoded := make([]string, len(value))
+		for i, element := range value {
+			encoded[i] = encode(element)
+		}
+
+		switch {
+		case !explode:
+			if operator.named {
+				result.WriteString(encode(name))
+				result.WriteString("=")
+			}
+
+			result.WriteString(strings.Join(encoded, ","))
+
+		case operator.named:
+			for i, element := range value {
+				if i > 0 {
+					result.WriteString(operator.separator)
+				}
+
+				writeNamed(name, element)
+			}
+
+		default:
+			result.WriteString(strings.Join(encoded, operator.separator))
+		}
+
+	case [][2]string:
+		if prefix > 0 {
+			return fmt.Errorf("%w: prefix applied to associative array %q", ErrInvalidURITemplate, name)
+		}
+
+		if !explode {
+			if operator.named {
+				result.WriteString(encode(name))
+				result.WriteString("=")
+			}
+
+			for i, pair := range value {
+				if i > 0 {
+					result.WriteString(",")
+				}
+
+				result.WriteString(encode(pair[0]))
+				result.WriteString(",")
+				result.WriteString(encode(pair[1]))
+			}
+
+			return nil
+		}
+
+		for i, pair := range value {
+			if i > 0 {
+				result.WriteString(operator.separator)
+			}
+
+			if operator.named {
+				writeNamed(pair[0], pair[1])
+			} else {
+				result.WriteString(encode(pair[0]))
+				result.WriteString("=")
+				result.WriteString(encode(pair[1]))
+			}
+		}
+	}
+
+	return nil
+}
+
+const uriReserved = ":/?#[]@!$&'()*+,;="
+
+// encodeURITemplateValue percent-encodes s, leaving unreserved characters, and reserved characters and existing
+// percent-encoded triplets if allowReserved is true.
+func encodeURITemplateValue(s string, allowReserved bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte("-._~", c) >= 0:
+			encoded.WriteByte(c)
+
+		case allowReserved && strings.IndexByte(uriReserved, c) >= 0:
+			encoded.WriteByte(c)
+
+		case allowReserved && c == '%' && i+2 < len(s) && isHexDigit(s[i+1]) && isHexDigit(s[i+2]):
+			encoded.WriteString(s[i : i+3])
+			i += 2
+
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+
+	return encoded.String()
+}
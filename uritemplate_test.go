@@ -0,0 +1,237 @@
+package qst_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/broothie/qst"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandURITemplate(t *testing.T) {
+	// Examples from RFC 6570 section 3.2, with associative arrays in key order.
+	vars := map[string]interface{}{
+		"count":      []string{"one", "two", "three"},
+		"dom":        []string{"example", "com"},
+		"dub":        "me/too",
+		"hello":      "Hello World!",
+		"half":       "50%",
+		"var":        "value",
+		"who":        "fred",
+		"base":       "http://example.com/home/",
+		"path":       "/foo/bar",
+		"list":       []interface{}{"red", "green", "blue"},
+		"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+		"v":          6,
+		"x":          1024,
+		"y":          768,
+		"empty":      "",
+		"empty_keys": map[string]string{},
+		"undef":      nil,
+	}
+
+	testCases := map[string]string{
+		// Level 1 and simple string expansion
+		"{var}":             "value",
+		"{hello}":           "Hello%20World%21",
+		"{half}":            "50%25",
+		"O{empty}X":         "OX",
+		"O{undef}X":         "OX",
+		"{x,y}":             "1024,768",
+		"{x,hello,y}":       "1024,Hello%20World%21,768",
+		"?{x,empty}":        "?1024,",
+		"?{x,undef}":        "?1024",
+		"?{undef,y}":        "?768",
+		"{var:3}":           "val",
+		"{var:30}":          "value",
+		"{list}":            "red,green,blue",
+		"{list*}":           "red,green,blue",
+		"{keys}":            "comma,%2C,dot,.,semi,%3B",
+		"{keys*}":           "comma=%2C,dot=.,semi=%3B",
+		"{count}":           "one,two,three",
+		"{/count*}":         "/one/two/three",
+		"{empty_keys}":      "",
+		"{?empty_keys*}":    "",
+		"{var}{empty}{var}": "valuevalue",
+
+		// Reserved expansion
+		"{+var}":              "value",
+		"{+hello}":            "Hello%20World!",
+		"{+half}":             "50%25",
+		"{base}index":         "http%3A%2F%2Fexample.com%2Fhome%2Findex",
+		"{+base}index":        "http://example.com/home/index",
+		"O{+empty}X":          "OX",
+		"{+path}/here":        "/foo/bar/here",
+		"here?ref={+path}":    "here?ref=/foo/bar",
+		"up{+path}{var}/here": "up/foo/barvalue/here",
+		"{+x,hello,y}":        "1024,Hello%20World!,768",
+		"{+path,x}/here":      "/foo/bar,1024/here",
+		"{+path:6}/here":      "/foo/b/here",
+		"{+list}":             "red,green,blue",
+		"{+list*}":            "red,green,blue",
+		"{+keys}":             "comma,,,dot,.,semi,;",
+		"{+keys*}":            "comma=,,dot=.,semi=;",
+
+		// Fragment expansion
+		"{#var}":         "#value",
+		"{#hello}":       "#Hello%20World!",
+		"{#half}":        "#50%25",
+		"foo{#empty}":    "foo#",
+		"foo{#undef}":    "foo",
+		"{#x,hello,y}":   "#1024,Hello%20World!,768",
+		"{#path,x}/here": "#/foo/bar,1024/here",
+		"{#path:6}/here": "#/foo/b/here",
+		"{#list}":        "#red,green,blue",
+		"{#list*}":       "#red,green,blue",
+		"{#keys}":        "#comma,,,dot,.,semi,;",
+		"{#keys*}":       "#comma=,,dot=.,semi=;",
+
+		// Label expansion
+		"{.who}":          ".fred",
+		"{.who,who}":      ".fred.fred",
+		"{.half,who}":     ".50%25.fred",
+		"www{.dom*}":      "www.example.com",
+		"X{.var}":         "X.value",
+		"X{.empty}":       "X.",
+		"X{.undef}":       "X",
+		"X{.var:3}":       "X.val",
+		"X{.list}":        "X.red,green,blue",
+		"X{.list*}":       "X.red.green.blue",
+		"X{.keys}":        "X.comma,%2C,dot,.,semi,%3B",
+		"X{.keys*}":       "X.comma=%2C.dot=..semi=%3B",
+		"X{.empty_keys}":  "X",
+		"X{.empty_keys*}": "X",
+
+		// Path segment expansion
+		"{/who}":          "/fred",
+		"{/who,who}":      "/fred/fred",
+		"{/half,who}":     "/50%25/fred",
+		"{/who,dub}":      "/fred/me%2Ftoo",
+		"{/var}":          "/value",
+		"{/var,empty}":    "/value/",
+		"{/var,undef}":    "/value",
+		"{/var,x}/here":   "/value/1024/here",
+		"{/var:1,var}":    "/v/value",
+		"{/list}":         "/red,green,blue",
+		"{/list*}":        "/red/green/blue",
+		"{/list*,path:4}": "/red/green/blue/%2Ffoo",
+		"{/keys}":         "/comma,%2C,dot,.,semi,%3B",
+		"{/keys*}":        "/comma=%2C/dot=./semi=%3B",
+
+		// Path-style parameter expansion
+		"{;who}":         ";who=fred",
+		"{;half}":        ";half=50%25",
+		"{;empty}":       ";empty",
+		"{;v,empty,who}": ";v=6;empty;who=fred",
+		"{;v,bar,who}":   ";v=6;who=fred",
+		"{;x,y}":         ";x=1024;y=768",
+		"{;x,y,empty}":   ";x=1024;y=768;empty",
+		"{;x,y,undef}":   ";x=1024;y=768",
+		"{;hello:5}":     ";hello=Hello",
+		"{;list}":        ";list=red,green,blue",
+		"{;list*}":       ";list=red;list=green;list=blue",
+		"{;keys}":        ";keys=comma,%2C,dot,.,semi,%3B",
+		"{;keys*}":       ";comma=%2C;dot=.;semi=%3B",
+
+		// Form-style query expansion
+		"{?who}":       "?who=fred",
+		"{?half}":      "?half=50%25",
+		"{?x,y}":       "?x=1024&y=768",
+		"{?x,y,empty}": "?x=1024&y=768&empty=",
+		"{?x,y,undef}": "?x=1024&y=768",
+		"{?var:3}":     "?var=val",
+		"{?list}":      "?list=red,green,blue",
+		"{?list*}":     "?list=red&list=green&list=blue",
+		"{?keys}":      "?keys=comma,%2C,dot,.,semi,%3B",
+		"{?keys*}":     "?comma=%2C&dot=.&semi=%3B",
+
+		// Form-style query continuation
+		"{&who}":         "&who=fred",
+		"{&half}":        "&half=50%25",
+		"?fixed=yes{&x}": "?fixed=yes&x=1024",
+		"{&x,y,empty}":   "&x=1024&y=768&empty=",
+		"{&var:3}":       "&var=val",
+		"{&list}":        "&list=red,green,blue",
+		"{&list*}":       "&list=red&list=green&list=blue",
+		"{&keys}":        "&keys=comma,%2C,dot,.,semi,%3B",
+		"{&keys*}":       "&comma=%2C&dot=.&semi=%3B",
+	}
+
+	for template, expected := range testCases {
+		t.Run(template, func(t *testing.T) {
+			expanded, err := qst.ExpandURITemplate(template, vars)
+			require.NoError(t, err)
+			assert.Equal(t, expected, expanded)
+		})
+	}
+
+	t.Run("unicode prefix", func(t *testing.T) {
+		expanded, err := qst.ExpandURITemplate("{name:3}", map[string]interface{}{"name": "Çéréal"})
+		require.NoError(t, err)
+		assert.Equal(t, "%C3%87%C3%A9r", expanded)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		templates := []string{
+			"{var",
+			"var}",
+			"{}",
+			"{=var}",
+			"{var:0}",
+			"{var:10000}",
+			"{var:x}",
+			"{va r}",
+			"{,var}",
+			"{list:3}",
+			"{keys:3}",
+		}
+
+		for _, template := range templates {
+			t.Run(template, func(t *testing.T) {
+				_, err := qst.ExpandURITemplate(template, vars)
+				assert.ErrorIs(t, err, qst.ErrInvalidURITemplate)
+			})
+		}
+	})
+}
+
+func TestWithURITemplate(t *testing.T) {
+	t.Run("appends path and query", func(t *testing.T) {
+		request, err := qst.NewGet("https://breakfast.com/api/?sort=name",
+			qst.WithURITemplate("/users/{id}/posts{?page,limit}", map[string]interface{}{"id": "a/b?c", "page": 2}),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://breakfast.com/api/users/a%2Fb%3Fc/posts?sort=name&page=2", request.URL.String())
+		assert.Equal(t, "/api/users/a/b?c/posts", request.URL.Path)
+	})
+
+	t.Run("absolute", func(t *testing.T) {
+		request, err := qst.NewGet("https://breakfast.com/api",
+			qst.WithURITemplate("https://{host}/cereals{#section}", map[string]interface{}{"host": "lunch.com", "section": "top"}),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://lunch.com/cereals#top", request.URL.String())
+		assert.Equal(t, "lunch.com", request.Host)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := qst.NewGet("https://breakfast.com/api", qst.WithURITemplate("/users/{id", nil))
+		assert.EqualError(t, err, "failed to apply option 0: invalid URI template: unclosed expression")
+	})
+}
+
+func ExampleWithURITemplate() {
+	request, _ := qst.NewGet("https://breakfast.com/api",
+		qst.WithURITemplate("/cereals/{id}/reviews{?page,limit}", map[string]interface{}{
+			"id":    "frosted/flakes",
+			"page":  2,
+			"limit": 10,
+		}),
+	)
+
+	fmt.Println(request.URL)
+	// Output: https://breakfast.com/api/cereals/frosted%2Fflakes/reviews?page=2&limit=10
+}